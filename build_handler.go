@@ -0,0 +1,252 @@
+package golph
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// buildReportTimeout bounds each harbormaster.sendmessage call. Reports don't
+// use the build's context, so that a build stopped by Shutdown is still
+// reported as failed rather than left working.
+const buildReportTimeout = 30 * time.Second
+
+// BuildStepURI is the query string to give a Harbormaster "Make HTTP Request"
+// build step, after the address of a BuildHandler, so that Harbormaster sends
+// everything a BuildRequest holds. Set the step to wait for a message when
+// it completes, as the handler reports the result with harbormaster.sendmessage.
+const BuildStepURI = "?target.phid=${target.phid}&build.id=${build.id}" +
+	"&buildable.diff=${buildable.diff}&buildable.revision=${buildable.revision}" +
+	"&buildable.commit=${buildable.commit}&repository.phid=${repository.phid}" +
+	"&repository.callsign=${repository.callsign}&repository.vcs=${repository.vcs}" +
+	"&repository.uri=${repository.uri}&initiator.phid=${initiator.phid}"
+
+// BuildRequest is a build Harbormaster asked for through a "Make HTTP Request"
+// build step. Fields that don't apply to the buildable, like DiffID for a
+// commit, are left empty.
+type BuildRequest struct {
+	TargetPHID         string
+	BuildID            int
+	DiffID             int
+	RevisionID         int
+	Commit             string
+	RepositoryPHID     string
+	RepositoryCallsign string
+	RepositoryVCS      string
+	RepositoryURI      string
+	InitiatorPHID      string
+}
+
+func (b BuildRequest) String() string {
+	return Stringify(b)
+}
+
+// BuildResult is what a build reports back to its build target.
+type BuildResult struct {
+	Failed bool
+	Unit   []UnitResult
+	Lint   []LintResult
+}
+
+// BuildRunner runs a build. Returning an error or panicking fails the build
+// target, with the error reported as a broken unit result. The context is
+// cancelled when the handler is shut down.
+type BuildRunner func(ctx context.Context, build *BuildRequest) (*BuildResult, error)
+
+// BuildHandler is an http.Handler for Harbormaster "Make HTTP Request" build
+// steps (see BuildStepURI). It answers every request at once and runs the build
+// in the background, reporting the target as working when it starts and as
+// passed or failed when it is done.
+type BuildHandler struct {
+	// Client reports build results through harbormaster.sendmessage.
+	Client *Client
+
+	// Runner runs each build.
+	Runner BuildRunner
+
+	// Username and Password, if set, are the HTTP basic auth credentials
+	// requests must carry, as configured on the build step.
+	Username string
+	Password string
+
+	// OnError, if set, is called when a build result can't be reported.
+	OnError func(*BuildRequest, error)
+
+	running sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBuildHandler returns a handler that runs builds with runner and reports
+// their results through client.
+func NewBuildHandler(client *Client, runner BuildRunner) *BuildHandler {
+	return &BuildHandler{Client: client, Runner: runner}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *BuildHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="golph"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	build, err := ParseBuildRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.start(build) {
+		http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// start runs build in the background, unless the handler has been shut down.
+// Holding mu while the build is added to running means Shutdown either turns
+// it away or waits for it.
+func (h *BuildHandler) start(build *BuildRequest) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	if h.ctx == nil {
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+	}
+
+	ctx := h.ctx
+	h.running.Add(1)
+	go func() {
+		defer h.running.Done()
+		h.run(ctx, build)
+	}()
+
+	return true
+}
+
+// Wait blocks until every build the handler started has been run and
+// reported, such as before shutting down.
+func (h *BuildHandler) Wait() {
+	h.running.Wait()
+}
+
+// Shutdown cancels the context of every running build, turns away new ones
+// and waits for those running to be reported.
+func (h *BuildHandler) Shutdown() {
+	h.mu.Lock()
+	h.closed = true
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.mu.Unlock()
+
+	h.Wait()
+}
+
+func (h *BuildHandler) authorized(r *http.Request) bool {
+	if h.Username == "" && h.Password == "" {
+		return true
+	}
+
+	username, password, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(h.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(h.Password)) == 1
+}
+
+// run runs a build and reports on it.
+func (h *BuildHandler) run(ctx context.Context, build *BuildRequest) {
+	h.report(build, &BuildMessage{Type: BuildMessageWork})
+
+	result, err := h.runner(ctx, build)
+	if result == nil {
+		result = &BuildResult{}
+	}
+
+	message := &BuildMessage{Type: BuildMessagePass, Unit: result.Unit, Lint: result.Lint}
+	if err != nil {
+		message.Type = BuildMessageFail
+		message.Unit = append(message.Unit, UnitResult{Name: "build", Result: UnitResultBroken, Details: err.Error()})
+	} else if result.Failed {
+		message.Type = BuildMessageFail
+	}
+
+	h.report(build, message)
+}
+
+// runner calls the Runner, turning a panic into an error so that the build
+// is still reported.
+func (h *BuildHandler) runner(ctx context.Context, build *BuildRequest) (result *BuildResult, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("build panicked: %v", p)
+		}
+	}()
+
+	return h.Runner(ctx, build)
+}
+
+func (h *BuildHandler) report(build *BuildRequest, message *BuildMessage) {
+	message.Receiver = build.TargetPHID
+
+	ctx, cancel := context.WithTimeout(context.Background(), buildReportTimeout)
+	defer cancel()
+
+	if _, err := h.Client.Harbormaster.SendMessage(ctx, message); err != nil && h.OnError != nil {
+		h.OnError(build, err)
+	}
+}
+
+// ParseBuildRequest reads a BuildRequest from the query string or form of a
+// request made by a "Make HTTP Request" build step.
+func ParseBuildRequest(r *http.Request) (*BuildRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	build := &BuildRequest{
+		TargetPHID:         r.Form.Get("target.phid"),
+		Commit:             r.Form.Get("buildable.commit"),
+		RepositoryPHID:     r.Form.Get("repository.phid"),
+		RepositoryCallsign: r.Form.Get("repository.callsign"),
+		RepositoryVCS:      r.Form.Get("repository.vcs"),
+		RepositoryURI:      r.Form.Get("repository.uri"),
+		InitiatorPHID:      r.Form.Get("initiator.phid"),
+	}
+
+	if build.TargetPHID == "" {
+		return nil, fmt.Errorf("target.phid is required")
+	}
+
+	ids := map[string]*int{
+		"build.id":           &build.BuildID,
+		"buildable.diff":     &build.DiffID,
+		"buildable.revision": &build.RevisionID,
+	}
+	for name, id := range ids {
+		value := r.Form.Get(name)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a number: %q", name, value)
+		}
+		*id = n
+	}
+
+	return build, nil
+}
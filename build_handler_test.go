@@ -0,0 +1,234 @@
+package golph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordMessages serves harbormaster.sendmessage, recording the message types
+// it is sent.
+func recordMessages(t *testing.T) func() []string {
+	var mu sync.Mutex
+	var types []string
+
+	mux.HandleFunc("/api/harbormaster.sendmessage", func(w http.ResponseWriter, r *http.Request) {
+		params := conduitParams(t, r)
+		if params["receiver"] != "PHID-HMBT-1" {
+			t.Errorf("Message sent to %v, expected PHID-HMBT-1", params["receiver"])
+		}
+
+		mu.Lock()
+		types = append(types, params["type"].(string))
+		if params["type"] == BuildMessageFail {
+			unit := params["unit"].([]interface{})
+			last := unit[len(unit)-1].(map[string]interface{})
+			types = append(types, fmt.Sprint(last["result"], ": ", last["details"]))
+		}
+		mu.Unlock()
+
+		fmt.Fprint(w, `{"result":null,"error_code":null,"error_info":null}`)
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return types
+	}
+}
+
+func TestBuildHandler_Pass(t *testing.T) {
+	setup()
+	defer teardown()
+
+	messages := recordMessages(t)
+
+	var got *BuildRequest
+	handler := NewBuildHandler(client, func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		got = build
+		return &BuildResult{Unit: []UnitResult{{Name: "TestGolph", Result: UnitResultPass}}}, nil
+	})
+
+	r := httptest.NewRequest("POST", "/build?target.phid=PHID-HMBT-1&build.id=12&buildable.diff=34&buildable.revision=56&buildable.commit=&repository.phid=PHID-REPO-1&repository.callsign=GOLPH&repository.vcs=git&repository.uri=https://example.com/golph.git&initiator.phid=PHID-USER-1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	handler.Wait()
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("BuildHandler answered %d, expected %d", w.Code, http.StatusAccepted)
+	}
+
+	expected := &BuildRequest{
+		TargetPHID:         "PHID-HMBT-1",
+		BuildID:            12,
+		DiffID:             34,
+		RevisionID:         56,
+		RepositoryPHID:     "PHID-REPO-1",
+		RepositoryCallsign: "GOLPH",
+		RepositoryVCS:      "git",
+		RepositoryURI:      "https://example.com/golph.git",
+		InitiatorPHID:      "PHID-USER-1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("BuildHandler ran %+v, expected %+v", got, expected)
+	}
+
+	if types := messages(); !reflect.DeepEqual(types, []string{BuildMessageWork, BuildMessagePass}) {
+		t.Errorf("BuildHandler sent %v, expected work then pass", types)
+	}
+}
+
+func TestBuildHandler_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	messages := recordMessages(t)
+
+	handler := NewBuildHandler(client, func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		return nil, errors.New("checkout failed")
+	})
+
+	r := httptest.NewRequest("POST", "/build", strings.NewReader("target.phid=PHID-HMBT-1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	handler.Wait()
+
+	expected := []string{BuildMessageWork, BuildMessageFail, "broken: checkout failed"}
+	if types := messages(); !reflect.DeepEqual(types, expected) {
+		t.Errorf("BuildHandler sent %v, expected %v", types, expected)
+	}
+}
+
+func TestBuildHandler_Panic(t *testing.T) {
+	setup()
+	defer teardown()
+
+	messages := recordMessages(t)
+
+	handler := NewBuildHandler(client, func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		panic("out of disk")
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/build?target.phid=PHID-HMBT-1", nil))
+	handler.Wait()
+
+	expected := []string{BuildMessageWork, BuildMessageFail, "broken: build panicked: out of disk"}
+	if types := messages(); !reflect.DeepEqual(types, expected) {
+		t.Errorf("BuildHandler sent %v, expected %v", types, expected)
+	}
+}
+
+func TestBuildHandler_Shutdown(t *testing.T) {
+	setup()
+	defer teardown()
+
+	messages := recordMessages(t)
+
+	started := make(chan struct{})
+	handler := &BuildHandler{Client: client, Runner: func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/build?target.phid=PHID-HMBT-1", nil))
+	<-started
+	handler.Shutdown()
+
+	expected := []string{BuildMessageWork, BuildMessageFail, "broken: context canceled"}
+	if types := messages(); !reflect.DeepEqual(types, expected) {
+		t.Errorf("BuildHandler sent %v, expected %v", types, expected)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/build?target.phid=PHID-HMBT-2", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("BuildHandler answered %d after Shutdown, expected %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestBuildHandler_ShutdownDuringRequests(t *testing.T) {
+	setup()
+	defer teardown()
+
+	messages := recordMessages(t)
+
+	handler := NewBuildHandler(client, func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		return nil, nil
+	})
+
+	var requests sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 20; i++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/build?target.phid=PHID-HMBT-1", nil))
+
+			if w.Code == http.StatusAccepted {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			} else if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("BuildHandler answered %d, expected %d or %d", w.Code, http.StatusAccepted, http.StatusServiceUnavailable)
+			}
+		}()
+	}
+
+	handler.Shutdown()
+	reported := len(messages())
+	requests.Wait()
+
+	// Every build accepted, even while Shutdown ran, was reported before it
+	// returned: a work and a pass message each.
+	if reported != 2*accepted {
+		t.Errorf("Shutdown returned after %d messages for %d accepted builds, expected %d", reported, accepted, 2*accepted)
+	}
+}
+
+func TestBuildHandler_BadRequests(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := NewBuildHandler(client, func(ctx context.Context, build *BuildRequest) (*BuildResult, error) {
+		t.Errorf("BuildHandler ran %+v, expected nothing to run", build)
+		return nil, nil
+	})
+	handler.Username = "harbormaster"
+	handler.Password = "secret"
+
+	cases := []struct {
+		url      string
+		username string
+		password string
+		code     int
+	}{
+		{"/build?target.phid=PHID-HMBT-1", "", "", http.StatusUnauthorized},
+		{"/build?target.phid=PHID-HMBT-1", "harbormaster", "wrong", http.StatusUnauthorized},
+		{"/build", "harbormaster", "secret", http.StatusBadRequest},
+		{"/build?target.phid=PHID-HMBT-1&build.id=twelve", "harbormaster", "secret", http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", c.url, nil)
+		if c.username != "" {
+			r.SetBasicAuth(c.username, c.password)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("BuildHandler answered %s with %d, expected %d", c.url, w.Code, c.code)
+		}
+	}
+	handler.Wait()
+}
@@ -0,0 +1,245 @@
+package golph
+
+import (
+	"context"
+	"sort"
+)
+
+const columnsSearchPath = "api/project.column.search"
+
+// Column represents a column on a project workboard.
+/*
+{
+  "id": 5,
+  "type": "PCOL",
+  "phid": "PHID-PCOL-1",
+  "fields": {
+    "name": "Backlog",
+    "proxyPHID": null,
+    "project": {"id": 1, "phid": "PHID-PROJ-1", "name": "Golph"},
+    "isHidden": false,
+    "sequence": 0,
+    "dateCreated": 1451337180,
+    "dateModified": 1451337180
+  }
+}
+*/
+type Column struct {
+	ID          int    `json:"id"`
+	PHID        string `json:"phid"`
+	Name        string `json:"name"`
+	ProjectPHID string `json:"projectPHID"`
+	ProjectName string `json:"projectName"`
+	// ProxyPHID is the milestone or subproject a column stands in for.
+	ProxyPHID    string     `json:"proxyPHID,omitempty"`
+	IsHidden     bool       `json:"isHidden"`
+	Sequence     int        `json:"sequence"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (c Column) String() string {
+	return Stringify(c)
+}
+
+// IsProxy reports whether the column stands in for a milestone or subproject,
+// so that tasks in it are the tasks tagged with that project.
+func (c Column) IsProxy() bool {
+	return c.ProxyPHID != ""
+}
+
+// ColumnSearchConstraints narrows down the results of project.column.search.
+type ColumnSearchConstraints struct {
+	IDs      []int    `json:"ids,omitempty"`
+	PHIDs    []string `json:"phids,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+// ColumnSearchRequest represents a request to project.column.search.
+type ColumnSearchRequest struct {
+	Constraints *ColumnSearchConstraints `json:"constraints,omitempty"`
+	Order       string                   `json:"order,omitempty"`
+	Before      string                   `json:"before,omitempty"`
+	After       string                   `json:"after,omitempty"`
+	Limit       int                      `json:"limit,omitempty"`
+}
+
+// ColumnMove places a task in a workboard column, optionally right before or
+// after another task in it.
+type ColumnMove struct {
+	ColumnPHID string `json:"columnPHID"`
+	BeforePHID string `json:"beforePHID,omitempty"`
+	AfterPHID  string `json:"afterPHID,omitempty"`
+}
+
+// MoveToColumns moves a task to columns on one or more workboards.
+func MoveToColumns(moves ...ColumnMove) Transaction {
+	return Transaction{Type: "column", Value: moves}
+}
+
+// MoveBefore moves a task to a workboard column, right above another task.
+func MoveBefore(columnPHID, taskPHID string) Transaction {
+	return MoveToColumns(ColumnMove{ColumnPHID: columnPHID, BeforePHID: taskPHID})
+}
+
+// MoveAfter moves a task to a workboard column, right below another task.
+func MoveAfter(columnPHID, taskPHID string) Transaction {
+	return MoveToColumns(ColumnMove{ColumnPHID: columnPHID, AfterPHID: taskPHID})
+}
+
+// Board is a project workboard: its columns in order, with the tasks in each.
+type Board struct {
+	ProjectPHID string
+	Columns     []BoardColumn
+}
+
+// BoardColumn is a column of a Board along with the tasks in it.
+type BoardColumn struct {
+	Column
+	Tasks []Task
+}
+
+// Column returns the column of the board with the given PHID, or nil.
+func (b *Board) Column(phid string) *BoardColumn {
+	for i := range b.Columns {
+		if b.Columns[i].PHID == phid {
+			return &b.Columns[i]
+		}
+	}
+	return nil
+}
+
+type columnSearchFields struct {
+	Name      string `json:"name"`
+	ProxyPHID string `json:"proxyPHID"`
+	Project   struct {
+		PHID string `json:"phid"`
+		Name string `json:"name"`
+	} `json:"project"`
+	IsHidden     bool       `json:"isHidden"`
+	Sequence     int        `json:"sequence"`
+	DateCreated  *Timestamp `json:"dateCreated"`
+	DateModified *Timestamp `json:"dateModified"`
+}
+
+type columnSearchData struct {
+	ID     int                `json:"id"`
+	PHID   string             `json:"phid"`
+	Fields columnSearchFields `json:"fields"`
+}
+
+func (d columnSearchData) toColumn() Column {
+	return Column{
+		ID:           d.ID,
+		PHID:         d.PHID,
+		Name:         d.Fields.Name,
+		ProjectPHID:  d.Fields.Project.PHID,
+		ProjectName:  d.Fields.Project.Name,
+		ProxyPHID:    d.Fields.ProxyPHID,
+		IsHidden:     d.Fields.IsHidden,
+		Sequence:     d.Fields.Sequence,
+		DateCreated:  d.Fields.DateCreated,
+		DateModified: d.Fields.DateModified,
+	}
+}
+
+type ColumnSearchResult struct {
+	Data   []columnSearchData `json:"data"`
+	Cursor PhabricatorCursor  `json:"cursor"`
+}
+
+type ColumnSearchResponse struct {
+	Result    ColumnSearchResult `json:"result"`
+	ErrorCode string             `json:"error_code,omitempty"`
+	ErrorInfo string             `json:"error_info,omitempty"`
+}
+
+// SearchColumns searches for workboard columns (through project.column.search),
+// following the cursor through every page of results.
+func (f *ProjectsServiceOp) SearchColumns(ctx context.Context, searchRequest *ColumnSearchRequest) ([]Column, *Response, error) {
+	page := ColumnSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	pager := NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", columnsSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(ColumnSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toColumn())
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+
+	var list []Column
+	for pager.Next() {
+		list = append(list, pager.Item().(Column))
+	}
+	return list, pager.Response(), pager.Err()
+}
+
+// Board returns the workboard of a project: its columns in board order, each
+// with the open and closed tasks in it ordered by priority.
+func (f *ProjectsServiceOp) Board(ctx context.Context, projectPHID string) (*Board, *Response, error) {
+	columns, resp, err := f.SearchColumns(ctx, &ColumnSearchRequest{
+		Constraints: &ColumnSearchConstraints{Projects: []string{projectPHID}},
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	sort.Sort(columnsBySequence(columns))
+
+	board := &Board{ProjectPHID: projectPHID}
+	for _, column := range columns {
+		board.Columns = append(board.Columns, BoardColumn{Column: column})
+	}
+
+	pager := f.client.Tasks.FindPager(ctx, &TaskFindRequest{
+		Constraints: &TaskFindConstraints{Projects: []string{projectPHID}},
+		Attachments: &TaskFindAttachments{Columns: true},
+		Order:       "priority",
+	})
+	for pager.Next() {
+		task := pager.Item().(Task)
+		for _, taskColumn := range task.Columns[projectPHID] {
+			if column := board.Column(taskColumn.PHID); column != nil {
+				column.Tasks = append(column.Tasks, task)
+			}
+		}
+	}
+
+	if pager.Response() != nil {
+		resp = pager.Response()
+	}
+
+	if err := pager.Err(); err != nil {
+		return nil, resp, err
+	}
+
+	return board, resp, nil
+}
+
+// columnsBySequence orders columns the way they appear on the board.
+type columnsBySequence []Column
+
+func (c columnsBySequence) Len() int      { return len(c) }
+func (c columnsBySequence) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c columnsBySequence) Less(i, j int) bool {
+	if c[i].Sequence != c[j].Sequence {
+		return c[i].Sequence < c[j].Sequence
+	}
+	return c[i].ID < c[j].ID
+}
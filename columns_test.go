@@ -0,0 +1,116 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const columnSearchJSON = `{"result":{"data":[{"id":7,"type":"PCOL","phid":"PHID-PCOL-3","fields":{"name":"Sprint 2","proxyPHID":"PHID-PROJ-2","project":{"id":1,"phid":"PHID-PROJ-1","name":"Golph"},"isHidden":false,"sequence":2,"dateCreated":1451337180,"dateModified":1451337180}},{"id":6,"type":"PCOL","phid":"PHID-PCOL-2","fields":{"name":"Doing","proxyPHID":null,"project":{"id":1,"phid":"PHID-PROJ-1","name":"Golph"},"isHidden":false,"sequence":1,"dateCreated":1451337180,"dateModified":1451337180}},{"id":5,"type":"PCOL","phid":"PHID-PCOL-1","fields":{"name":"Backlog","proxyPHID":null,"project":{"id":1,"phid":"PHID-PROJ-1","name":"Golph"},"isHidden":false,"sequence":0,"dateCreated":1451337180,"dateModified":1451337180}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`
+
+func TestProjects_SearchColumns(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.column.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"projects":["PHID-PROJ-1"]}}`)
+		fmt.Fprint(w, columnSearchJSON)
+	})
+
+	columns, _, err := client.Projects.SearchColumns(ctx, &ColumnSearchRequest{
+		Constraints: &ColumnSearchConstraints{Projects: []string{"PHID-PROJ-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Projects.SearchColumns returned error: %v", err)
+	}
+
+	if len(columns) != 3 {
+		t.Fatalf("Projects.SearchColumns returned %d columns, expected 3", len(columns))
+	}
+
+	created := &Timestamp{time.Unix(1451337180, 0)}
+	expected := Column{
+		ID:           7,
+		PHID:         "PHID-PCOL-3",
+		Name:         "Sprint 2",
+		ProjectPHID:  "PHID-PROJ-1",
+		ProjectName:  "Golph",
+		ProxyPHID:    "PHID-PROJ-2",
+		Sequence:     2,
+		DateCreated:  created,
+		DateModified: created,
+	}
+	if !reflect.DeepEqual(columns[0], expected) {
+		t.Errorf("Projects.SearchColumns returned %+v, expected %+v", columns[0], expected)
+	}
+
+	if !columns[0].IsProxy() || columns[1].IsProxy() {
+		t.Errorf("Column.IsProxy() should only be true for the milestone column")
+	}
+}
+
+func TestProjects_Board(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.column.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, columnSearchJSON)
+	})
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"projects":["PHID-PROJ-1"]},"attachments":{"columns":true},"order":"priority"}`)
+		fmt.Fprint(w, `{"result":{"data":[`+
+			`{"id":1,"phid":"PHID-TASK-1","fields":{"name":"Urgent"},"attachments":{"columns":{"boards":{"PHID-PROJ-1":{"columns":[{"id":6,"phid":"PHID-PCOL-2","name":"Doing"}]}}}}},`+
+			`{"id":2,"phid":"PHID-TASK-2","fields":{"name":"Someday"},"attachments":{"columns":{"boards":{"PHID-PROJ-1":{"columns":[{"id":5,"phid":"PHID-PCOL-1","name":"Backlog"}]},"PHID-PROJ-9":{"columns":[{"id":90,"phid":"PHID-PCOL-90","name":"Elsewhere"}]}}}}},`+
+			`{"id":3,"phid":"PHID-TASK-3","fields":{"name":"Also doing"},"attachments":{"columns":{"boards":{"PHID-PROJ-1":{"columns":[{"id":6,"phid":"PHID-PCOL-2","name":"Doing"}]}}}}}`+
+			`],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	board, _, err := client.Projects.Board(ctx, "PHID-PROJ-1")
+	if err != nil {
+		t.Fatalf("Projects.Board returned error: %v", err)
+	}
+
+	got := map[string][]string{}
+	var order []string
+	for _, column := range board.Columns {
+		order = append(order, column.Name)
+		for _, task := range column.Tasks {
+			got[column.Name] = append(got[column.Name], task.Title)
+		}
+	}
+
+	if expected := []string{"Backlog", "Doing", "Sprint 2"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Projects.Board returned columns %v, expected %v", order, expected)
+	}
+
+	expected := map[string][]string{"Backlog": {"Someday"}, "Doing": {"Urgent", "Also doing"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Projects.Board returned tasks %v, expected %v", got, expected)
+	}
+
+	if board.Column("PHID-PCOL-90") != nil {
+		t.Errorf("Board.Column returned a column from another board")
+	}
+}
+
+func TestMoveBefore(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"objectIdentifier":"T1","transactions":[{"type":"column","value":[{"columnPHID":"PHID-PCOL-2","beforePHID":"PHID-TASK-3"}]},{"type":"column","value":[{"columnPHID":"PHID-PCOL-1","afterPHID":"PHID-TASK-2"}]}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":1,"phid":"PHID-TASK-1"},"transactions":[]},"error_code":null,"error_info":null}`)
+	})
+
+	_, _, err := client.Tasks.Edit(ctx, &TaskEditRequest{
+		ObjectIdentifier: "T1",
+		Transactions:     []Transaction{MoveBefore("PHID-PCOL-2", "PHID-TASK-3"), MoveAfter("PHID-PCOL-1", "PHID-TASK-2")},
+	})
+	if err != nil {
+		t.Errorf("Tasks.Edit returned error: %v", err)
+	}
+}
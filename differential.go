@@ -0,0 +1,856 @@
+package golph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const revisionsSearchPath = "api/differential.revision.search"
+const revisionsEditMethod = "differential.revision.edit"
+const diffsSearchPath = "api/differential.diff.search"
+const inlinesCreatePath = "api/differential.createinline"
+const rawDiffsCreatePath = "api/differential.createrawdiff"
+const diffPropertySetPath = "api/differential.setdiffproperty"
+const diffsQueryPath = "api/differential.querydiffs"
+const rawDiffsGetPath = "api/differential.getrawdiff"
+
+// DifferentialService is an interface for interfacing with code review (Differential)
+// See: https://secure.phabricator.com/conduit/ (and search for differential)
+type DifferentialService interface {
+	SearchRevisions(context.Context, *RevisionSearchRequest) ([]Revision, *Response, error)
+	SearchRevisionsPager(context.Context, *RevisionSearchRequest) *Pager
+	EditRevision(context.Context, *RevisionEditRequest) (*EditResult, *Response, error)
+	SearchDiffs(context.Context, *DiffSearchRequest) ([]Diff, *Response, error)
+	CreateInline(context.Context, *InlineCommentRequest) (*InlineComment, *Response, error)
+	CreateRawDiff(context.Context, *RawDiffCreateRequest) (*RawDiff, *Response, error)
+	SetDiffProperty(context.Context, int, string, interface{}) (*Response, error)
+	SubmitDiff(context.Context, *DiffSubmitRequest) (*DiffSubmission, *Response, error)
+	QueryDiffs(context.Context, *DiffQueryRequest) ([]QueriedDiff, *Response, error)
+	GetRawDiff(context.Context, int) (string, *Response, error)
+	PatchRevision(context.Context, string, *PatchRequest) (*PatchResult, *Response, error)
+}
+
+// DifferentialServiceOp handles communication with the conduit methods
+type DifferentialServiceOp struct {
+	client *Client
+}
+
+var _ DifferentialService = &DifferentialServiceOp{}
+
+// Revision represents a Differential revision.
+/*
+{
+  "id": 123,
+  "type": "DREV",
+  "phid": "PHID-DREV-1",
+  "fields": {
+    "title": "Make golph review code",
+    "uri": "https://phabricator.example.com/D123",
+    "authorPHID": "PHID-USER-1",
+    "status": {"value": "needs-review", "name": "Needs Review", "closed": false},
+    "repositoryPHID": "PHID-REPO-1",
+    "diffPHID": "PHID-DIFF-1",
+    "summary": "...",
+    "testPlan": "...",
+    "isDraft": false,
+    "dateCreated": 1451337180,
+    "dateModified": 1451337180
+  },
+  "attachments": {
+    "reviewers": {"reviewers": [{"reviewerPHID": "PHID-USER-2", "status": "accepted", "isBlocking": false, "actorPHID": "PHID-USER-2"}]},
+    "subscribers": {"subscriberPHIDs": ["PHID-USER-3"]},
+    "projects": {"projectPHIDs": ["PHID-PROJ-1"]}
+  }
+}
+*/
+type Revision struct {
+	ID           int        `json:"id"`
+	PHID         PHID       `json:"phid"`
+	Title        string     `json:"title"`
+	URI          string     `json:"uri"`
+	Author       string     `json:"authorPHID"`
+	Status       string     `json:"status"`
+	StatusName   string     `json:"statusName"`
+	IsClosed     bool       `json:"isClosed"`
+	Repository   string     `json:"repositoryPHID"`
+	Diff         string     `json:"diffPHID"`
+	Summary      string     `json:"summary"`
+	TestPlan     string     `json:"testPlan"`
+	IsDraft      bool       `json:"isDraft"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+	Reviewers    []Reviewer `json:"reviewers,omitempty"`
+	Subscribers  []string   `json:"subscriberPHIDs,omitempty"`
+	Projects     []string   `json:"projectPHIDs,omitempty"`
+}
+
+func (f Revision) String() string {
+	return Stringify(f)
+}
+
+// Reviewer is a user or project asked to review a Revision.
+type Reviewer struct {
+	PHID       string `json:"reviewerPHID"`
+	Status     string `json:"status"`
+	IsBlocking bool   `json:"isBlocking"`
+	Actor      string `json:"actorPHID"`
+}
+
+// Diff represents a single diff attached to a Revision.
+type Diff struct {
+	ID           int        `json:"id"`
+	PHID         PHID       `json:"phid"`
+	Revision     string     `json:"revisionPHID"`
+	Author       string     `json:"authorPHID"`
+	Repository   string     `json:"repositoryPHID"`
+	Refs         []DiffRef  `json:"refs"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f Diff) String() string {
+	return Stringify(f)
+}
+
+// DiffRef is a branch, bookmark or base commit the diff was made against.
+type DiffRef struct {
+	Type       string `json:"type"`
+	Name       string `json:"name,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// RevisionSearchConstraints narrows down the results of differential.revision.search.
+type RevisionSearchConstraints struct {
+	IDs              []int    `json:"ids,omitempty"`
+	PHIDs            []string `json:"phids,omitempty"`
+	ResponsiblePHIDs []string `json:"responsiblePHIDs,omitempty"`
+	AuthorPHIDs      []string `json:"authorPHIDs,omitempty"`
+	ReviewerPHIDs    []string `json:"reviewerPHIDs,omitempty"`
+	RepositoryPHIDs  []string `json:"repositoryPHIDs,omitempty"`
+	Statuses         []string `json:"statuses,omitempty"`
+	Query            string   `json:"query,omitempty"`
+}
+
+// RevisionSearchAttachments asks differential.revision.search for extra data.
+type RevisionSearchAttachments struct {
+	Reviewers   bool `json:"reviewers,omitempty"`
+	Subscribers bool `json:"subscribers,omitempty"`
+	Projects    bool `json:"projects,omitempty"`
+}
+
+// RevisionSearchRequest represents a request to differential.revision.search.
+type RevisionSearchRequest struct {
+	QueryKey    string                     `json:"queryKey,omitempty"`
+	Constraints *RevisionSearchConstraints `json:"constraints,omitempty"`
+	Attachments *RevisionSearchAttachments `json:"attachments,omitempty"`
+	Order       string                     `json:"order,omitempty"`
+	Before      string                     `json:"before,omitempty"`
+	After       string                     `json:"after,omitempty"`
+	Limit       int                        `json:"limit,omitempty"`
+}
+
+// RevisionEditRequest represents a request to differential.revision.edit. Leave
+// ObjectIdentifier empty to create a new revision.
+type RevisionEditRequest EditRequest
+
+// DiffSearchConstraints narrows down the results of differential.diff.search.
+type DiffSearchConstraints struct {
+	IDs           []int    `json:"ids,omitempty"`
+	PHIDs         []string `json:"phids,omitempty"`
+	RevisionPHIDs []string `json:"revisionPHIDs,omitempty"`
+}
+
+// DiffSearchRequest represents a request to differential.diff.search.
+type DiffSearchRequest struct {
+	Constraints *DiffSearchConstraints `json:"constraints,omitempty"`
+	Order       string                 `json:"order,omitempty"`
+	Before      string                 `json:"before,omitempty"`
+	After       string                 `json:"after,omitempty"`
+	Limit       int                    `json:"limit,omitempty"`
+}
+
+// InlineCommentRequest represents a request to leave an inline comment on a
+// diff. Inline comments stay drafts until a comment transaction is applied to
+// the revision.
+type InlineCommentRequest struct {
+	RevisionID int    `json:"revisionID"`
+	DiffID     int    `json:"diffID,omitempty"`
+	FilePath   string `json:"filePath"`
+	IsNewFile  bool   `json:"isNewFile"`
+	LineNumber int    `json:"lineNumber"`
+	LineLength int    `json:"lineLength,omitempty"`
+	Content    string `json:"content"`
+}
+
+// InlineComment is a comment left on a line of a diff.
+type InlineComment struct {
+	ID         int    `json:"id"`
+	Author     string `json:"authorPHID"`
+	FilePath   string `json:"filePath"`
+	IsNewFile  bool   `json:"isNewFile"`
+	LineNumber int    `json:"lineNumber"`
+	LineLength int    `json:"lineLength"`
+	DiffID     int    `json:"diffID"`
+	Content    string `json:"content"`
+}
+
+// RawDiffCreateRequest represents a request to differential.createrawdiff.
+type RawDiffCreateRequest struct {
+	Diff           string `json:"diff"`
+	RepositoryPHID string `json:"repositoryPHID,omitempty"`
+	ViewPolicy     string `json:"viewPolicy,omitempty"`
+}
+
+// RawDiff is a diff created from raw unified diff text.
+type RawDiff struct {
+	ID   int    `json:"id"`
+	PHID string `json:"phid"`
+	URI  string `json:"uri"`
+}
+
+// DiffPropertyRequest represents a request to differential.setdiffproperty.
+type DiffPropertyRequest struct {
+	DiffID int    `json:"diff_id"`
+	Name   string `json:"name"`
+	Data   string `json:"data"`
+}
+
+// DiffSubmitRequest represents a request to post code for review, the way
+// `arc diff` does. The diff is sent through differential.createrawdiff, which
+// builds the changesets on the server; the older differential.creatediff, which
+// takes changesets built by the client, isn't supported.
+type DiffSubmitRequest struct {
+	// Diff is raw unified diff text. When empty, it is generated by diffing
+	// Base against Head in the local git repository at RepoPath.
+	Diff     string
+	RepoPath string
+
+	// Base defaults to the merge-base of Head and the upstream of the
+	// current branch. An empty Head diffs Base against the working tree.
+	Base string
+	Head string
+
+	RepositoryPHID string
+	ViewPolicy     string
+
+	// Revision is the revision to update, such as "D123". When empty, a new
+	// revision is created if Title is set, otherwise only the diff is created.
+	Revision  string
+	Title     string
+	Summary   string
+	TestPlan  string
+	Reviewers []string
+
+	// Transactions are applied to the revision along with the new diff.
+	Transactions []Transaction
+}
+
+// DiffSubmission is the diff, and revision if any, created by SubmitDiff.
+type DiffSubmission struct {
+	DiffID       int
+	DiffPHID     string
+	DiffURI      string
+	RevisionID   int
+	RevisionPHID string
+	// Revision is the monogram of the revision, such as "D123".
+	Revision string
+	URI      string
+}
+
+// DiffQueryRequest represents a request to differential.querydiffs.
+type DiffQueryRequest struct {
+	IDs         []int `json:"ids,omitempty"`
+	RevisionIDs []int `json:"revisionIDs,omitempty"`
+}
+
+// QueriedDiff is a diff as returned by the older differential.querydiffs,
+// which still carries the source control details `arc patch` relies on.
+type QueriedDiff struct {
+	ID                        string `json:"id"`
+	RevisionID                string `json:"revisionID"`
+	SourceControlBaseRevision string `json:"sourceControlBaseRevision"`
+	SourceControlSystem       string `json:"sourceControlSystem"`
+	Branch                    string `json:"branch"`
+	Bookmark                  string `json:"bookmark"`
+	AuthorName                string `json:"authorName"`
+	AuthorEmail               string `json:"authorEmail"`
+}
+
+// RawDiffRequest represents a request to differential.getrawdiff.
+type RawDiffRequest struct {
+	DiffID int `json:"diffID"`
+}
+
+// PatchRequest represents a request to apply a revision to a local git
+// repository, the way `arc patch` does.
+type PatchRequest struct {
+	RepoPath string
+
+	// Branch to create for the patch. Defaults to "arcpatch-D123".
+	Branch string
+
+	// Base to branch from. Defaults to the base commit recorded with the diff
+	// when the repository has it, and the current HEAD otherwise.
+	Base string
+
+	// Commit the patch after applying it, using the revision title.
+	Commit bool
+}
+
+// PatchResult describes a revision applied by PatchRevision.
+type PatchResult struct {
+	Revision string
+	DiffID   int
+	Branch   string
+	Base     string
+	// Conflicts lists files left with conflict markers.
+	Conflicts []string
+}
+
+// AcceptRevision accepts a revision.
+func AcceptRevision() Transaction {
+	return Transaction{Type: "accept", Value: true}
+}
+
+// RejectRevision requests changes to a revision.
+func RejectRevision() Transaction {
+	return Transaction{Type: "reject", Value: true}
+}
+
+// RequestReview puts a revision back in front of its reviewers.
+func RequestReview() Transaction {
+	return Transaction{Type: "request-review", Value: true}
+}
+
+// AbandonRevision abandons a revision.
+func AbandonRevision() Transaction {
+	return Transaction{Type: "abandon", Value: true}
+}
+
+// CommentOnRevision adds a comment, publishing any draft inline comments.
+func CommentOnRevision(comment string) Transaction {
+	return Comment(comment)
+}
+
+// AddReviewers adds users or projects as reviewers of a revision.
+func AddReviewers(phids ...string) Transaction {
+	return Transaction{Type: "reviewers.add", Value: phids}
+}
+
+type revisionSearchStatus struct {
+	Value  string `json:"value"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+}
+
+type revisionSearchFields struct {
+	Title        string               `json:"title"`
+	URI          string               `json:"uri"`
+	AuthorPHID   string               `json:"authorPHID"`
+	Status       revisionSearchStatus `json:"status"`
+	Repository   string               `json:"repositoryPHID"`
+	Diff         string               `json:"diffPHID"`
+	Summary      string               `json:"summary"`
+	TestPlan     string               `json:"testPlan"`
+	IsDraft      bool                 `json:"isDraft"`
+	DateCreated  *Timestamp           `json:"dateCreated"`
+	DateModified *Timestamp           `json:"dateModified"`
+}
+
+type revisionSearchAttachments struct {
+	Reviewers struct {
+		Reviewers []Reviewer `json:"reviewers"`
+	} `json:"reviewers"`
+	Subscribers struct {
+		SubscriberPHIDs []string `json:"subscriberPHIDs"`
+	} `json:"subscribers"`
+	Projects struct {
+		ProjectPHIDs []string `json:"projectPHIDs"`
+	} `json:"projects"`
+}
+
+type revisionSearchData struct {
+	ID          int                       `json:"id"`
+	PHID        PHID                      `json:"phid"`
+	Fields      revisionSearchFields      `json:"fields"`
+	Attachments revisionSearchAttachments `json:"attachments"`
+}
+
+func (d revisionSearchData) toRevision() Revision {
+	return Revision{
+		ID:           d.ID,
+		PHID:         d.PHID,
+		Title:        d.Fields.Title,
+		URI:          d.Fields.URI,
+		Author:       d.Fields.AuthorPHID,
+		Status:       d.Fields.Status.Value,
+		StatusName:   d.Fields.Status.Name,
+		IsClosed:     d.Fields.Status.Closed,
+		Repository:   d.Fields.Repository,
+		Diff:         d.Fields.Diff,
+		Summary:      d.Fields.Summary,
+		TestPlan:     d.Fields.TestPlan,
+		IsDraft:      d.Fields.IsDraft,
+		DateCreated:  d.Fields.DateCreated,
+		DateModified: d.Fields.DateModified,
+		Reviewers:    d.Attachments.Reviewers.Reviewers,
+		Subscribers:  d.Attachments.Subscribers.SubscriberPHIDs,
+		Projects:     d.Attachments.Projects.ProjectPHIDs,
+	}
+}
+
+type RevisionSearchResult struct {
+	Data   []revisionSearchData `json:"data"`
+	Cursor PhabricatorCursor    `json:"cursor"`
+}
+
+type RevisionSearchResponse struct {
+	Result    RevisionSearchResult `json:"result"`
+	ErrorCode string               `json:"error_code,omitempty"`
+	ErrorInfo string               `json:"error_info,omitempty"`
+}
+
+type diffSearchFields struct {
+	RevisionPHID   string     `json:"revisionPHID"`
+	AuthorPHID     string     `json:"authorPHID"`
+	RepositoryPHID string     `json:"repositoryPHID"`
+	Refs           []DiffRef  `json:"refs"`
+	DateCreated    *Timestamp `json:"dateCreated"`
+	DateModified   *Timestamp `json:"dateModified"`
+}
+
+type diffSearchData struct {
+	ID     int              `json:"id"`
+	PHID   PHID             `json:"phid"`
+	Fields diffSearchFields `json:"fields"`
+}
+
+func (d diffSearchData) toDiff() Diff {
+	return Diff{
+		ID:           d.ID,
+		PHID:         d.PHID,
+		Revision:     d.Fields.RevisionPHID,
+		Author:       d.Fields.AuthorPHID,
+		Repository:   d.Fields.RepositoryPHID,
+		Refs:         d.Fields.Refs,
+		DateCreated:  d.Fields.DateCreated,
+		DateModified: d.Fields.DateModified,
+	}
+}
+
+type DiffSearchResult struct {
+	Data   []diffSearchData  `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
+}
+
+type DiffSearchResponse struct {
+	Result    DiffSearchResult `json:"result"`
+	ErrorCode string           `json:"error_code,omitempty"`
+	ErrorInfo string           `json:"error_info,omitempty"`
+}
+
+type RawDiffResponse struct {
+	Result    RawDiff `json:"result"`
+	ErrorCode string  `json:"error_code,omitempty"`
+	ErrorInfo string  `json:"error_info,omitempty"`
+}
+
+// DiffMap is a set of diffs keyed by diff ID.
+type DiffMap map[string]QueriedDiff
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *DiffMap) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = DiffMap{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]QueriedDiff)(m))
+}
+
+type DiffQueryResponse struct {
+	Result    DiffMap `json:"result"`
+	ErrorCode string  `json:"error_code,omitempty"`
+	ErrorInfo string  `json:"error_info,omitempty"`
+}
+
+type RawDiffTextResponse struct {
+	Result    string `json:"result"`
+	ErrorCode string `json:"error_code,omitempty"`
+	ErrorInfo string `json:"error_info,omitempty"`
+}
+
+type InlineCommentResponse struct {
+	Result    InlineComment `json:"result"`
+	ErrorCode string        `json:"error_code,omitempty"`
+	ErrorInfo string        `json:"error_info,omitempty"`
+}
+
+// SearchRevisions searches for revisions (through differential.revision.search).
+func (f *DifferentialServiceOp) SearchRevisions(ctx context.Context, searchRequest *RevisionSearchRequest) ([]Revision, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", revisionsSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(RevisionSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Revision
+	for _, data := range root.Result.Data {
+		list = append(list, data.toRevision())
+	}
+	return list, resp, err
+}
+
+// SearchRevisionsPager returns a Pager that follows the differential.revision.search
+// cursor through every page of results. Items are Revision values.
+func (f *DifferentialServiceOp) SearchRevisionsPager(ctx context.Context, searchRequest *RevisionSearchRequest) *Pager {
+	page := RevisionSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", revisionsSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(RevisionSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toRevision())
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// EditRevision applies transactions to a revision (through differential.revision.edit).
+func (f *DifferentialServiceOp) EditRevision(ctx context.Context, editRequest *RevisionEditRequest) (*EditResult, *Response, error) {
+	if editRequest == nil {
+		editRequest = &RevisionEditRequest{}
+	}
+
+	return f.client.Edit(ctx, revisionsEditMethod, editRequest.ObjectIdentifier, editRequest.Transactions)
+}
+
+// SearchDiffs searches for diffs (through differential.diff.search).
+func (f *DifferentialServiceOp) SearchDiffs(ctx context.Context, searchRequest *DiffSearchRequest) ([]Diff, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", diffsSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(DiffSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Diff
+	for _, data := range root.Result.Data {
+		list = append(list, data.toDiff())
+	}
+	return list, resp, err
+}
+
+// CreateInline leaves a draft inline comment on a diff (through differential.createinline).
+func (f *DifferentialServiceOp) CreateInline(ctx context.Context, inlineRequest *InlineCommentRequest) (*InlineComment, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", inlinesCreatePath, inlineRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(InlineCommentResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
+
+// CreateRawDiff creates a diff from raw unified diff text (through
+// differential.createrawdiff). Phabricator parses the changesets itself.
+func (f *DifferentialServiceOp) CreateRawDiff(ctx context.Context, createRequest *RawDiffCreateRequest) (*RawDiff, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", rawDiffsCreatePath, createRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(RawDiffResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
+
+// SetDiffProperty attaches a named, JSON encoded property such as
+// "local:commits" to a diff (through differential.setdiffproperty).
+func (f *DifferentialServiceOp) SetDiffProperty(ctx context.Context, diffID int, name string, data interface{}) (*Response, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	propertyRequest := &DiffPropertyRequest{
+		DiffID: diffID,
+		Name:   name,
+		Data:   string(encoded),
+	}
+
+	req, err := f.client.NewJSONRequest(ctx, "POST", diffPropertySetPath, propertyRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.client.Do(req, nil)
+}
+
+// SubmitDiff posts a diff for review and, when asked to, creates or updates a
+// revision with it.
+func (f *DifferentialServiceOp) SubmitDiff(ctx context.Context, submitRequest *DiffSubmitRequest) (*DiffSubmission, *Response, error) {
+	if submitRequest == nil {
+		submitRequest = &DiffSubmitRequest{}
+	}
+
+	raw := submitRequest.Diff
+
+	var commits map[string]LocalCommit
+	if raw == "" {
+		if submitRequest.RepoPath == "" {
+			return nil, nil, errors.New("SubmitDiff needs either a Diff or a RepoPath")
+		}
+
+		var err error
+		base := submitRequest.Base
+		if base == "" {
+			base, err = GitMergeBase(ctx, submitRequest.RepoPath, "@{upstream}", submitRequest.Head)
+			if err != nil {
+				return nil, nil, fmt.Errorf("SubmitDiff needs a Base, as the branch has no upstream to diff against: %v", err)
+			}
+		}
+
+		raw, err = GitDiff(ctx, submitRequest.RepoPath, base, submitRequest.Head)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if submitRequest.Head != "" {
+			commits, err = GitCommits(ctx, submitRequest.RepoPath, base, submitRequest.Head)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil, errors.New("SubmitDiff was given an empty diff")
+	}
+
+	diff, resp, err := f.CreateRawDiff(ctx, &RawDiffCreateRequest{
+		Diff:           raw,
+		RepositoryPHID: submitRequest.RepositoryPHID,
+		ViewPolicy:     submitRequest.ViewPolicy,
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	submission := &DiffSubmission{
+		DiffID:   diff.ID,
+		DiffPHID: diff.PHID,
+		DiffURI:  diff.URI,
+	}
+
+	if len(commits) > 0 {
+		resp, err = f.SetDiffProperty(ctx, diff.ID, "local:commits", commits)
+		if err != nil {
+			return submission, resp, err
+		}
+	}
+
+	if submitRequest.Revision == "" && submitRequest.Title == "" {
+		return submission, resp, nil
+	}
+
+	transactions := []Transaction{{Type: "update", Value: diff.PHID}}
+	if submitRequest.Title != "" {
+		transactions = append(transactions, Transaction{Type: "title", Value: submitRequest.Title})
+	}
+	if submitRequest.Summary != "" {
+		transactions = append(transactions, Transaction{Type: "summary", Value: submitRequest.Summary})
+	}
+	if submitRequest.TestPlan != "" {
+		transactions = append(transactions, Transaction{Type: "testPlan", Value: submitRequest.TestPlan})
+	}
+	if len(submitRequest.Reviewers) > 0 {
+		transactions = append(transactions, AddReviewers(submitRequest.Reviewers...))
+	}
+	transactions = append(transactions, submitRequest.Transactions...)
+
+	result, resp, err := f.EditRevision(ctx, &RevisionEditRequest{
+		ObjectIdentifier: submitRequest.Revision,
+		Transactions:     transactions,
+	})
+	if err != nil {
+		return submission, resp, err
+	}
+
+	submission.RevisionID = result.Object.ID
+	submission.RevisionPHID = result.Object.PHID
+	submission.Revision = fmt.Sprintf("D%d", result.Object.ID)
+	submission.URI = f.client.BaseURL.ResolveReference(&url.URL{Path: submission.Revision}).String()
+
+	return submission, resp, err
+}
+
+// QueryDiffs looks up diffs by ID or revision ID (through differential.querydiffs).
+// Diffs are returned newest first.
+func (f *DifferentialServiceOp) QueryDiffs(ctx context.Context, queryRequest *DiffQueryRequest) ([]QueriedDiff, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", diffsQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(DiffQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var ids []int
+	for id := range root.Result {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, resp, err
+		}
+		ids = append(ids, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+	var list []QueriedDiff
+	for _, id := range ids {
+		list = append(list, root.Result[strconv.Itoa(id)])
+	}
+	return list, resp, err
+}
+
+// GetRawDiff returns the unified diff text of a diff (through differential.getrawdiff).
+func (f *DifferentialServiceOp) GetRawDiff(ctx context.Context, diffID int) (string, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", rawDiffsGetPath, &RawDiffRequest{DiffID: diffID})
+	if err != nil {
+		return "", nil, err
+	}
+
+	root := new(RawDiffTextResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return "", resp, err
+	}
+
+	return root.Result, resp, err
+}
+
+// PatchRevision applies the latest diff of a revision, such as "D123", to a
+// new branch of a local git repository. When the patch does not apply
+// cleanly, the result lists the conflicting files and a *PatchConflictError
+// is returned.
+func (f *DifferentialServiceOp) PatchRevision(ctx context.Context, revision string, patchRequest *PatchRequest) (*PatchResult, *Response, error) {
+	if patchRequest == nil || patchRequest.RepoPath == "" {
+		return nil, nil, errors.New("PatchRevision needs a RepoPath")
+	}
+
+	revisionID, err := strconv.Atoi(strings.TrimPrefix(revision, "D"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%q is not a revision like D123", revision)
+	}
+
+	diffs, resp, err := f.QueryDiffs(ctx, &DiffQueryRequest{RevisionIDs: []int{revisionID}})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if len(diffs) < 1 {
+		return nil, resp, fmt.Errorf("D%d has no diffs", revisionID)
+	}
+	diff := diffs[0]
+
+	diffID, err := strconv.Atoi(diff.ID)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	patch, resp, err := f.GetRawDiff(ctx, diffID)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	result := &PatchResult{
+		Revision: fmt.Sprintf("D%d", revisionID),
+		DiffID:   diffID,
+		Branch:   patchRequest.Branch,
+		Base:     patchRequest.Base,
+	}
+
+	if result.Branch == "" {
+		result.Branch = "arcpatch-" + result.Revision
+	}
+
+	if result.Base == "" && diff.SourceControlBaseRevision != "" && gitHasCommit(ctx, patchRequest.RepoPath, diff.SourceControlBaseRevision) {
+		result.Base = diff.SourceControlBaseRevision
+	}
+
+	err = GitApply(ctx, patchRequest.RepoPath, result.Branch, result.Base, patch)
+	if conflictErr, ok := err.(*PatchConflictError); ok {
+		result.Conflicts = conflictErr.Files
+	}
+	if err != nil {
+		return result, resp, err
+	}
+
+	if !patchRequest.Commit {
+		return result, resp, nil
+	}
+
+	message := result.Revision
+	revisions, resp, err := f.SearchRevisions(ctx, &RevisionSearchRequest{
+		Constraints: &RevisionSearchConstraints{IDs: []int{revisionID}},
+	})
+	if err != nil {
+		return result, resp, err
+	}
+	if len(revisions) > 0 {
+		message = fmt.Sprintf("%s\n\nDifferential Revision: %s", revisions[0].Title, revisions[0].URI)
+	}
+
+	args := []string{"commit", "-q", "-m", message}
+	if diff.AuthorName != "" && diff.AuthorEmail != "" {
+		args = append(args, "--author", fmt.Sprintf("%s <%s>", diff.AuthorName, diff.AuthorEmail))
+	}
+
+	if _, err := runGit(ctx, patchRequest.RepoPath, args...); err != nil {
+		return result, resp, err
+	}
+
+	return result, resp, nil
+}
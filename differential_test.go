@@ -0,0 +1,424 @@
+package golph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	searchRevisionJSON = `{"result":{"data":[{"id":123,"type":"DREV","phid":"PHID-DREV-1","fields":{"title":"Make golph review code","uri":"https://phabricator.example.com/D123","authorPHID":"PHID-USER-1","status":{"value":"needs-review","name":"Needs Review","closed":false,"color.ansi":"magenta"},"repositoryPHID":"PHID-REPO-1","diffPHID":"PHID-DIFF-1","summary":"A summary","testPlan":"go test","isDraft":false,"holdAsDraft":false,"dateCreated":1451337180,"dateModified":1451337280,"policy":{"view":"users","edit":"users"}},"attachments":{"reviewers":{"reviewers":[{"reviewerPHID":"PHID-USER-2","status":"accepted","isBlocking":false,"actorPHID":"PHID-USER-2"}]},"subscribers":{"subscriberPHIDs":["PHID-USER-3"],"subscriberCount":1,"viewerIsSubscribed":false},"projects":{"projectPHIDs":["PHID-PROJ-1"]}}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`
+	searchDiffJSON     = `{"result":{"data":[{"id":42,"type":"DIFF","phid":"PHID-DIFF-1","fields":{"revisionPHID":"PHID-DREV-1","authorPHID":"PHID-USER-1","repositoryPHID":"PHID-REPO-1","refs":[{"type":"branch","name":"feature"},{"type":"base","identifier":"abc123"}],"dateCreated":1451337180,"dateModified":1451337180,"policy":{"view":"users"}},"attachments":{}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`
+	editRevisionJSON   = `{"result":{"object":{"id":123,"phid":"PHID-DREV-1"},"transactions":[{"phid":"PHID-XACT-DREV-1"},{"phid":"PHID-XACT-DREV-2"}]},"error_code":null,"error_info":null}`
+)
+
+func TestDifferential_SearchRevisions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	searchRequest := &RevisionSearchRequest{
+		Constraints: &RevisionSearchConstraints{
+			AuthorPHIDs: []string{"PHID-USER-1"},
+			Statuses:    []string{"needs-review"},
+		},
+		Attachments: &RevisionSearchAttachments{Reviewers: true, Subscribers: true, Projects: true},
+	}
+
+	mux.HandleFunc("/api/differential.revision.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"authorPHIDs":["PHID-USER-1"],"statuses":["needs-review"]},"attachments":{"reviewers":true,"subscribers":true,"projects":true}}`)
+		fmt.Fprint(w, searchRevisionJSON)
+	})
+
+	revisions, _, err := client.Differential.SearchRevisions(ctx, searchRequest)
+	if err != nil {
+		t.Errorf("Differential.SearchRevisions returned error: %v", err)
+	}
+
+	expected := []Revision{
+		{
+			ID:           123,
+			PHID:         "PHID-DREV-1",
+			Title:        "Make golph review code",
+			URI:          "https://phabricator.example.com/D123",
+			Author:       "PHID-USER-1",
+			Status:       "needs-review",
+			StatusName:   "Needs Review",
+			Repository:   "PHID-REPO-1",
+			Diff:         "PHID-DIFF-1",
+			Summary:      "A summary",
+			TestPlan:     "go test",
+			DateCreated:  &Timestamp{time.Unix(1451337180, 0)},
+			DateModified: &Timestamp{time.Unix(1451337280, 0)},
+			Reviewers:    []Reviewer{{PHID: "PHID-USER-2", Status: "accepted", Actor: "PHID-USER-2"}},
+			Subscribers:  []string{"PHID-USER-3"},
+			Projects:     []string{"PHID-PROJ-1"},
+		},
+	}
+
+	if !reflect.DeepEqual(revisions, expected) {
+		t.Errorf("Differential.SearchRevisions returned:\n%+v\nExpected:\n%+v", revisions, expected)
+	}
+}
+
+func TestDifferential_EditRevision(t *testing.T) {
+	setup()
+	defer teardown()
+
+	editRequest := &RevisionEditRequest{
+		ObjectIdentifier: "D123",
+		Transactions: []Transaction{
+			AddReviewers("PHID-USER-2", "PHID-PROJ-1"),
+			CommentOnRevision("Looks good"),
+			AcceptRevision(),
+		},
+	}
+
+	mux.HandleFunc("/api/differential.revision.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"D123","transactions":[{"type":"reviewers.add","value":["PHID-USER-2","PHID-PROJ-1"]},{"type":"comment","value":"Looks good"},{"type":"accept","value":true}]}`)
+		fmt.Fprint(w, editRevisionJSON)
+	})
+
+	result, _, err := client.Differential.EditRevision(ctx, editRequest)
+	if err != nil {
+		t.Errorf("Differential.EditRevision returned error: %v", err)
+	}
+
+	expected := &EditResult{
+		Object:       EditObject{ID: 123, PHID: "PHID-DREV-1"},
+		Transactions: []EditTransaction{{PHID: "PHID-XACT-DREV-1"}, {PHID: "PHID-XACT-DREV-2"}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Differential.EditRevision returned %+v, expected %+v", result, expected)
+	}
+}
+
+func TestDifferential_SearchDiffs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/differential.diff.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"revisionPHIDs":["PHID-DREV-1"]}}`)
+		fmt.Fprint(w, searchDiffJSON)
+	})
+
+	diffs, _, err := client.Differential.SearchDiffs(ctx, &DiffSearchRequest{
+		Constraints: &DiffSearchConstraints{RevisionPHIDs: []string{"PHID-DREV-1"}},
+	})
+	if err != nil {
+		t.Errorf("Differential.SearchDiffs returned error: %v", err)
+	}
+
+	expected := []Diff{
+		{
+			ID:           42,
+			PHID:         "PHID-DIFF-1",
+			Revision:     "PHID-DREV-1",
+			Author:       "PHID-USER-1",
+			Repository:   "PHID-REPO-1",
+			Refs:         []DiffRef{{Type: "branch", Name: "feature"}, {Type: "base", Identifier: "abc123"}},
+			DateCreated:  &Timestamp{time.Unix(1451337180, 0)},
+			DateModified: &Timestamp{time.Unix(1451337180, 0)},
+		},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Differential.SearchDiffs returned:\n%+v\nExpected:\n%+v", diffs, expected)
+	}
+}
+
+func TestDifferential_CreateInline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	inlineRequest := &InlineCommentRequest{
+		RevisionID: 123,
+		FilePath:   "golph.go",
+		IsNewFile:  true,
+		LineNumber: 10,
+		Content:    "Nit: typo",
+	}
+
+	mux.HandleFunc("/api/differential.createinline", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"revisionID":123,"filePath":"golph.go","isNewFile":true,"lineNumber":10,"content":"Nit: typo"}`)
+		fmt.Fprint(w, `{"result":{"id":7,"authorPHID":"PHID-USER-1","filePath":"golph.go","isNewFile":true,"lineNumber":10,"lineLength":0,"diffID":42,"content":"Nit: typo"},"error_code":null,"error_info":null}`)
+	})
+
+	inline, _, err := client.Differential.CreateInline(ctx, inlineRequest)
+	if err != nil {
+		t.Errorf("Differential.CreateInline returned error: %v", err)
+	}
+
+	expected := &InlineComment{ID: 7, Author: "PHID-USER-1", FilePath: "golph.go", IsNewFile: true, LineNumber: 10, DiffID: 42, Content: "Nit: typo"}
+	if !reflect.DeepEqual(inline, expected) {
+		t.Errorf("Differential.CreateInline returned %+v, expected %+v", inline, expected)
+	}
+}
+
+func TestDifferential_SubmitDiff(t *testing.T) {
+	setup()
+	defer teardown()
+
+	raw := "diff --git a/README b/README\n--- a/README\n+++ b/README\n@@ -1 +1,2 @@\n hello\n+world\n"
+
+	mux.HandleFunc("/api/differential.createrawdiff", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, fmt.Sprintf(`{"diff":%q,"repositoryPHID":"PHID-REPO-1"}`, raw))
+		fmt.Fprint(w, `{"result":{"id":43,"phid":"PHID-DIFF-43","uri":"https://phabricator.example.com/differential/diff/43/"},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/differential.revision.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"transactions":[{"type":"update","value":"PHID-DIFF-43"},{"type":"title","value":"Say hello to the world"},{"type":"testPlan","value":"Read it"},{"type":"reviewers.add","value":["PHID-USER-2"]}]}`)
+		fmt.Fprint(w, editRevisionJSON)
+	})
+
+	submission, _, err := client.Differential.SubmitDiff(ctx, &DiffSubmitRequest{
+		Diff:           raw,
+		RepositoryPHID: "PHID-REPO-1",
+		Title:          "Say hello to the world",
+		TestPlan:       "Read it",
+		Reviewers:      []string{"PHID-USER-2"},
+	})
+	if err != nil {
+		t.Fatalf("Differential.SubmitDiff returned error: %v", err)
+	}
+
+	expected := &DiffSubmission{
+		DiffID:       43,
+		DiffPHID:     "PHID-DIFF-43",
+		DiffURI:      "https://phabricator.example.com/differential/diff/43/",
+		RevisionID:   123,
+		RevisionPHID: "PHID-DREV-1",
+		Revision:     "D123",
+		URI:          server.URL + "/D123",
+	}
+	if !reflect.DeepEqual(submission, expected) {
+		t.Errorf("Differential.SubmitDiff returned %+v, expected %+v", submission, expected)
+	}
+}
+
+func TestDifferential_SubmitDiffFromGit(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/differential.createrawdiff", func(w http.ResponseWriter, r *http.Request) {
+		if diff, _ := conduitParams(t, r)["diff"].(string); !strings.Contains(diff, "+world") {
+			t.Errorf("Params diff = %q, expected the git diff", diff)
+		}
+		fmt.Fprint(w, `{"result":{"id":43,"phid":"PHID-DIFF-43","uri":"https://phabricator.example.com/differential/diff/43/"},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/differential.setdiffproperty", func(w http.ResponseWriter, r *http.Request) {
+		params := conduitParams(t, r)
+		if params["diff_id"] != float64(43) || params["name"] != "local:commits" {
+			t.Errorf("Unexpected diff property %v on diff %v", params["name"], params["diff_id"])
+		}
+
+		var commits map[string]LocalCommit
+		data, _ := params["data"].(string)
+		if err := json.Unmarshal([]byte(data), &commits); err != nil {
+			t.Errorf("Params data is not JSON: %v", err)
+		}
+		if _, ok := commits[head]; !ok {
+			t.Errorf("Form data = %+v, expected commit %s", commits, head)
+		}
+		fmt.Fprint(w, `{"result":null,"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/differential.revision.edit", func(w http.ResponseWriter, r *http.Request) {
+		if id := conduitParams(t, r)["objectIdentifier"]; id != "D123" {
+			t.Errorf("Params objectIdentifier = %v, expected D123", id)
+		}
+		fmt.Fprint(w, editRevisionJSON)
+	})
+
+	submission, _, err := client.Differential.SubmitDiff(ctx, &DiffSubmitRequest{
+		RepoPath: dir,
+		Base:     base,
+		Head:     head,
+		Revision: "D123",
+	})
+	if err != nil {
+		t.Fatalf("Differential.SubmitDiff returned error: %v", err)
+	}
+
+	if submission.Revision != "D123" || submission.DiffID != 43 {
+		t.Errorf("Differential.SubmitDiff returned %+v", submission)
+	}
+}
+
+func TestDifferential_SubmitDiffDefaultBase(t *testing.T) {
+	dir, base, _ := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/differential.createrawdiff", func(w http.ResponseWriter, r *http.Request) {
+		if diff, _ := conduitParams(t, r)["diff"].(string); !strings.Contains(diff, "+world") {
+			t.Errorf("Params diff = %q, expected the changes since the upstream branch", diff)
+		}
+		fmt.Fprint(w, `{"result":{"id":44,"phid":"PHID-DIFF-44","uri":"https://phabricator.example.com/differential/diff/44/"},"error_code":null,"error_info":null}`)
+	})
+
+	if _, _, err := client.Differential.SubmitDiff(ctx, &DiffSubmitRequest{RepoPath: dir}); err == nil {
+		t.Errorf("Differential.SubmitDiff without a Base or an upstream returned no error")
+	}
+
+	for _, args := range [][]string{{"branch", "upstream", base}, {"branch", "--set-upstream-to=upstream"}} {
+		if _, err := runGit(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	submission, _, err := client.Differential.SubmitDiff(ctx, &DiffSubmitRequest{RepoPath: dir})
+	if err != nil {
+		t.Fatalf("Differential.SubmitDiff returned error: %v", err)
+	}
+	if submission.DiffID != 44 {
+		t.Errorf("Differential.SubmitDiff returned %+v", submission)
+	}
+}
+
+func TestDifferential_SubmitDiffNil(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if _, _, err := client.Differential.SubmitDiff(ctx, nil); err == nil {
+		t.Errorf("Differential.SubmitDiff(nil) returned no error")
+	}
+}
+
+func TestDifferential_QueryDiffs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/differential.querydiffs", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"revisionIDs":[123]}`)
+		fmt.Fprint(w, `{"result":{"9":{"id":"9","revisionID":"123","sourceControlBaseRevision":"abc","branch":"feature"},"10":{"id":"10","revisionID":"123","sourceControlBaseRevision":"def","branch":"feature"}},"error_code":null,"error_info":null}`)
+	})
+
+	diffs, _, err := client.Differential.QueryDiffs(ctx, &DiffQueryRequest{RevisionIDs: []int{123}})
+	if err != nil {
+		t.Fatalf("Differential.QueryDiffs returned error: %v", err)
+	}
+
+	expected := []QueriedDiff{
+		{ID: "10", RevisionID: "123", SourceControlBaseRevision: "def", Branch: "feature"},
+		{ID: "9", RevisionID: "123", SourceControlBaseRevision: "abc", Branch: "feature"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Differential.QueryDiffs returned %+v, expected %+v", diffs, expected)
+	}
+}
+
+// patchServer serves D123 with a single diff made from base..head.
+func patchServer(t *testing.T, dir, base, head string) {
+	patch, err := GitDiff(ctx, dir, base, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/api/differential.querydiffs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"result":{"43":{"id":"43","revisionID":"123","sourceControlBaseRevision":"%s","authorName":"Golph","authorEmail":"golph@example.com"}},"error_code":null,"error_info":null}`, base)
+	})
+
+	mux.HandleFunc("/api/differential.getrawdiff", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"diffID":43}`)
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": patch, "error_code": nil, "error_info": nil})
+	})
+}
+
+func TestDifferential_PatchRevision(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	setup()
+	defer teardown()
+
+	patchServer(t, dir, base, head)
+	mux.HandleFunc("/api/differential.revision.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, searchRevisionJSON)
+	})
+
+	result, _, err := client.Differential.PatchRevision(ctx, "D123", &PatchRequest{RepoPath: dir, Commit: true})
+	if err != nil {
+		t.Fatalf("Differential.PatchRevision returned error: %v", err)
+	}
+
+	expected := &PatchResult{Revision: "D123", DiffID: 43, Branch: "arcpatch-D123", Base: base}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Differential.PatchRevision returned %+v, expected %+v", result, expected)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "README"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello\nworld\n" {
+		t.Errorf("README = %q after patching", content)
+	}
+
+	message, err := runGit(ctx, dir, "log", "-1", "--format=%B%an")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(message, "Make golph review code\n\nDifferential Revision: https://phabricator.example.com/D123") || !strings.HasSuffix(message, "Golph\n") {
+		t.Errorf("Commit message = %q", message)
+	}
+}
+
+func TestDifferential_PatchRevisionWithoutRepoPath(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/differential.querydiffs", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("PatchRevision queried diffs without a RepoPath")
+	})
+
+	for _, patchRequest := range []*PatchRequest{nil, {Branch: "arcpatch-D123"}} {
+		if _, _, err := client.Differential.PatchRevision(ctx, "D123", patchRequest); err == nil {
+			t.Errorf("Differential.PatchRevision(%+v) returned no error", patchRequest)
+		}
+	}
+}
+
+func TestDifferential_PatchRevisionConflict(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("goodbye\nworld\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(ctx, dir, "commit", "-q", "-a", "-m", "Say goodbye"); err != nil {
+		t.Fatal(err)
+	}
+
+	setup()
+	defer teardown()
+
+	patchServer(t, dir, base, head)
+
+	result, _, err := client.Differential.PatchRevision(ctx, "D123", &PatchRequest{RepoPath: dir, Branch: "conflicted", Base: "master"})
+	if _, ok := err.(*PatchConflictError); !ok {
+		t.Fatalf("Differential.PatchRevision returned %v, expected a *PatchConflictError", err)
+	}
+
+	if !reflect.DeepEqual(result.Conflicts, []string{"README"}) || result.Branch != "conflicted" {
+		t.Errorf("Differential.PatchRevision returned %+v", result)
+	}
+}
@@ -0,0 +1,182 @@
+package golph
+
+import (
+	"context"
+	"sort"
+)
+
+const edgesSearchPath = "api/edge.search"
+
+// EdgesService is an interface for reading the relationships between objects,
+// like a task's parents and subtasks or the revisions that fix it.
+// See: https://secure.phabricator.com/conduit/method/edge.search/
+type EdgesService interface {
+	Search(context.Context, *EdgeSearchRequest) ([]Edge, *Response, error)
+	SearchPager(context.Context, *EdgeSearchRequest) *Pager
+	Graph(context.Context, *EdgeSearchRequest) (EdgeGraph, *Response, error)
+}
+
+// EdgesServiceOp handles communication with the conduit methods
+type EdgesServiceOp struct {
+	client *Client
+}
+
+var _ EdgesService = &EdgesServiceOp{}
+
+// Kinds of edge edge.search can look for. Most come in pairs, so that a
+// task's subtasks are the tasks it is a parent of.
+const (
+	EdgeTypeTaskParent     = "task.parent"
+	EdgeTypeTaskSubtask    = "task.subtask"
+	EdgeTypeTaskRevision   = "task.revision"
+	EdgeTypeTaskCommit     = "task.commit"
+	EdgeTypeTaskDuplicate  = "task.duplicate"
+	EdgeTypeTaskMergedIn   = "task.merged-in"
+	EdgeTypeRevisionTask   = "revision.task"
+	EdgeTypeRevisionParent = "revision.parent"
+	EdgeTypeRevisionChild  = "revision.child"
+	EdgeTypeRevisionCommit = "revision.commit"
+	EdgeTypeCommitRevision = "commit.revision"
+	EdgeTypeCommitTask     = "commit.task"
+	EdgeTypeMention        = "mention"
+	EdgeTypeMentionedIn    = "mentioned-in"
+)
+
+// Edge is a relationship of one kind from one object to another.
+/*
+{
+  "sourcePHID": "PHID-TASK-1",
+  "edgeType": "task.subtask",
+  "destinationPHID": "PHID-TASK-2"
+}
+*/
+type Edge struct {
+	SourcePHID      string `json:"sourcePHID"`
+	Type            string `json:"edgeType"`
+	DestinationPHID string `json:"destinationPHID"`
+}
+
+func (e Edge) String() string {
+	return Stringify(e)
+}
+
+// EdgeSearchRequest represents a request to edge.search. SourcePHIDs and Types
+// are required; DestinationPHIDs narrows the results down to edges pointing at
+// the given objects.
+type EdgeSearchRequest struct {
+	SourcePHIDs      []string `json:"sourcePHIDs"`
+	Types            []string `json:"types"`
+	DestinationPHIDs []string `json:"destinationPHIDs,omitempty"`
+	Before           string   `json:"before,omitempty"`
+	After            string   `json:"after,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+}
+
+type EdgeSearchResult struct {
+	Data   []Edge            `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
+}
+
+type EdgeSearchResponse struct {
+	Result    EdgeSearchResult `json:"result"`
+	ErrorCode string           `json:"error_code,omitempty"`
+	ErrorInfo string           `json:"error_info,omitempty"`
+}
+
+// EdgeGraph holds edges by their source PHID, such as to walk a task's
+// dependencies.
+type EdgeGraph map[string][]Edge
+
+// NewEdgeGraph returns a graph of the given edges.
+func NewEdgeGraph(edges []Edge) EdgeGraph {
+	graph := EdgeGraph{}
+	for _, edge := range edges {
+		graph.Add(edge)
+	}
+	return graph
+}
+
+// Add adds an edge to the graph, unless it is already there.
+func (g EdgeGraph) Add(edge Edge) {
+	for _, e := range g[edge.SourcePHID] {
+		if e == edge {
+			return
+		}
+	}
+	g[edge.SourcePHID] = append(g[edge.SourcePHID], edge)
+}
+
+// Destinations returns the sorted PHIDs that source has an edge of the given
+// type to, like the subtasks of a task for EdgeTypeTaskSubtask.
+func (g EdgeGraph) Destinations(sourcePHID string, edgeType string) []string {
+	var phids []string
+	for _, edge := range g[sourcePHID] {
+		if edge.Type == edgeType {
+			phids = append(phids, edge.DestinationPHID)
+		}
+	}
+	sort.Strings(phids)
+	return phids
+}
+
+// Search lists the edges of the given types from the source objects (through
+// edge.search).
+func (f *EdgesServiceOp) Search(ctx context.Context, searchRequest *EdgeSearchRequest) ([]Edge, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", edgesSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(EdgeSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return root.Result.Data, resp, err
+}
+
+// SearchPager returns a Pager that follows the edge.search cursor through
+// every page of results. Items are Edge values.
+func (f *EdgesServiceOp) SearchPager(ctx context.Context, searchRequest *EdgeSearchRequest) *Pager {
+	page := EdgeSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", edgesSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(EdgeSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, edge := range root.Result.Data {
+			items = append(items, edge)
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// Graph reads every page of edges matching the request into an EdgeGraph.
+func (f *EdgesServiceOp) Graph(ctx context.Context, searchRequest *EdgeSearchRequest) (EdgeGraph, *Response, error) {
+	graph := EdgeGraph{}
+
+	pager := f.SearchPager(ctx, searchRequest)
+	for pager.Next() {
+		graph.Add(pager.Item().(Edge))
+	}
+
+	if err := pager.Err(); err != nil {
+		return nil, pager.Response(), err
+	}
+	return graph, pager.Response(), nil
+}
@@ -0,0 +1,83 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestEdges_Search(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/edge.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"sourcePHIDs":["PHID-TASK-1"],"types":["task.subtask","task.revision"]}`)
+		fmt.Fprint(w, `{"result":{"data":[{"sourcePHID":"PHID-TASK-1","edgeType":"task.subtask","destinationPHID":"PHID-TASK-2"},{"sourcePHID":"PHID-TASK-1","edgeType":"task.revision","destinationPHID":"PHID-DREV-1"}],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	edges, _, err := client.Edges.Search(ctx, &EdgeSearchRequest{
+		SourcePHIDs: []string{"PHID-TASK-1"},
+		Types:       []string{EdgeTypeTaskSubtask, EdgeTypeTaskRevision},
+	})
+	if err != nil {
+		t.Fatalf("Edges.Search returned error: %v", err)
+	}
+
+	expected := []Edge{
+		{SourcePHID: "PHID-TASK-1", Type: EdgeTypeTaskSubtask, DestinationPHID: "PHID-TASK-2"},
+		{SourcePHID: "PHID-TASK-1", Type: EdgeTypeTaskRevision, DestinationPHID: "PHID-DREV-1"},
+	}
+	if !reflect.DeepEqual(edges, expected) {
+		t.Errorf("Edges.Search returned %+v, expected %+v", edges, expected)
+	}
+}
+
+func TestEdges_Graph(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/edge.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		after, _ := conduitParams(t, r)["after"].(string)
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"result":{"data":[{"sourcePHID":"PHID-TASK-1","edgeType":"task.subtask","destinationPHID":"PHID-TASK-3"}],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
+		case "1":
+			fmt.Fprint(w, `{"result":{"data":[{"sourcePHID":"PHID-TASK-1","edgeType":"task.subtask","destinationPHID":"PHID-TASK-2"},{"sourcePHID":"PHID-TASK-2","edgeType":"task.parent","destinationPHID":"PHID-TASK-1"}],"cursor":{"limit":1,"after":null,"before":"1"}},"error_code":null,"error_info":null}`)
+		default:
+			t.Errorf("Unexpected cursor %q", after)
+		}
+	})
+
+	graph, _, err := client.Edges.Graph(ctx, &EdgeSearchRequest{
+		SourcePHIDs: []string{"PHID-TASK-1", "PHID-TASK-2"},
+		Types:       []string{EdgeTypeTaskSubtask, EdgeTypeTaskParent},
+		Limit:       1,
+	})
+	if err != nil {
+		t.Fatalf("Edges.Graph returned error: %v", err)
+	}
+
+	if subtasks, expected := graph.Destinations("PHID-TASK-1", EdgeTypeTaskSubtask), []string{"PHID-TASK-2", "PHID-TASK-3"}; !reflect.DeepEqual(subtasks, expected) {
+		t.Errorf("Subtasks of PHID-TASK-1 are %v, expected %v", subtasks, expected)
+	}
+
+	if parents, expected := graph.Destinations("PHID-TASK-2", EdgeTypeTaskParent), []string{"PHID-TASK-1"}; !reflect.DeepEqual(parents, expected) {
+		t.Errorf("Parents of PHID-TASK-2 are %v, expected %v", parents, expected)
+	}
+
+	if parents := graph.Destinations("PHID-TASK-1", EdgeTypeTaskParent); parents != nil {
+		t.Errorf("Parents of PHID-TASK-1 are %v, expected none", parents)
+	}
+}
+
+func TestEdgeGraph_Add(t *testing.T) {
+	edge := Edge{SourcePHID: "PHID-TASK-1", Type: EdgeTypeMention, DestinationPHID: "PHID-TASK-2"}
+	graph := NewEdgeGraph([]Edge{edge, edge})
+
+	if len(graph["PHID-TASK-1"]) != 1 {
+		t.Errorf("Graph holds %v, expected the edge once", graph["PHID-TASK-1"])
+	}
+}
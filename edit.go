@@ -0,0 +1,130 @@
+package golph
+
+import (
+	"context"
+)
+
+// Transaction is a single change applied to an object through one of the
+// modern *.edit Conduit methods, such as differential.revision.edit.
+type Transaction struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// EditRequest represents a request to any of the *.edit Conduit methods. Leave
+// ObjectIdentifier empty to create a new object.
+type EditRequest struct {
+	Transactions     []Transaction `json:"transactions"`
+	ObjectIdentifier string        `json:"objectIdentifier,omitempty"`
+}
+
+// EditObject identifies the object an *.edit call created or changed.
+type EditObject struct {
+	ID   int    `json:"id"`
+	PHID string `json:"phid"`
+}
+
+// EditTransaction identifies a transaction an *.edit call applied.
+type EditTransaction struct {
+	PHID string `json:"phid"`
+}
+
+// EditResult is what every *.edit method returns.
+type EditResult struct {
+	Object       EditObject        `json:"object"`
+	Transactions []EditTransaction `json:"transactions"`
+}
+
+// TransactionPHIDs returns the PHIDs of the transactions that were applied.
+func (r EditResult) TransactionPHIDs() []string {
+	phids := make([]string, 0, len(r.Transactions))
+	for _, txn := range r.Transactions {
+		phids = append(phids, txn.PHID)
+	}
+	return phids
+}
+
+type EditResponse struct {
+	Result    EditResult `json:"result"`
+	ErrorCode string     `json:"error_code,omitempty"`
+	ErrorInfo string     `json:"error_info,omitempty"`
+}
+
+// Edit applies transactions to the object named by objectIdentifier, a PHID,
+// ID or monogram such as "T123", through the *.edit Conduit method given, such
+// as "maniphest.edit". An empty objectIdentifier creates a new object.
+func (c *Client) Edit(ctx context.Context, method, objectIdentifier string, transactions []Transaction) (*EditResult, *Response, error) {
+	editRequest := &EditRequest{
+		Transactions:     transactions,
+		ObjectIdentifier: objectIdentifier,
+	}
+
+	req, err := c.NewJSONRequest(ctx, "POST", "api/"+method, editRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(EditResponse)
+	resp, err := c.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
+
+// SetTitle changes the title, or name, of an object.
+func SetTitle(title string) Transaction {
+	return Transaction{Type: "title", Value: title}
+}
+
+// SetDescription changes the description of an object.
+func SetDescription(description string) Transaction {
+	return Transaction{Type: "description", Value: description}
+}
+
+// SetStatus changes the status of an object, such as "resolved" for a task.
+func SetStatus(status string) Transaction {
+	return Transaction{Type: "status", Value: status}
+}
+
+// Comment adds a comment to an object.
+func Comment(comment string) Transaction {
+	return Transaction{Type: "comment", Value: comment}
+}
+
+// AddProjects tags an object with projects.
+func AddProjects(projectPHIDs ...string) Transaction {
+	return Transaction{Type: "projects.add", Value: projectPHIDs}
+}
+
+// RemoveProjects removes project tags from an object.
+func RemoveProjects(projectPHIDs ...string) Transaction {
+	return Transaction{Type: "projects.remove", Value: projectPHIDs}
+}
+
+// AddSubscribers subscribes users or projects to an object.
+func AddSubscribers(phids ...string) Transaction {
+	return Transaction{Type: "subscribers.add", Value: phids}
+}
+
+// RemoveSubscribers unsubscribes users or projects from an object.
+func RemoveSubscribers(phids ...string) Transaction {
+	return Transaction{Type: "subscribers.remove", Value: phids}
+}
+
+// SetViewPolicy changes who can see an object, given as a policy such as
+// "users" or a project PHID.
+func SetViewPolicy(policy string) Transaction {
+	return Transaction{Type: "view", Value: policy}
+}
+
+// SetEditPolicy changes who can edit an object.
+func SetEditPolicy(policy string) Transaction {
+	return Transaction{Type: "edit", Value: policy}
+}
+
+// MoveToColumn moves a task to a workboard column.
+func MoveToColumn(columnPHID string) Transaction {
+	return Transaction{Type: "column", Value: []string{columnPHID}}
+}
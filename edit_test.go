@@ -0,0 +1,52 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Edit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/paste.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"transactions":[{"type":"title","value":"Stack trace"},{"type":"projects.add","value":["PHID-PROJ-1"]},{"type":"subscribers.remove","value":["PHID-USER-2"]},{"type":"status","value":"active"},{"type":"comment","value":"From the nightly build"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":12,"phid":"PHID-PSTE-1"},"transactions":[{"phid":"PHID-XACT-PSTE-1"},{"phid":"PHID-XACT-PSTE-2"}]},"error_code":null,"error_info":null}`)
+	})
+
+	result, _, err := client.Edit(ctx, "paste.edit", "", []Transaction{
+		SetTitle("Stack trace"),
+		AddProjects("PHID-PROJ-1"),
+		RemoveSubscribers("PHID-USER-2"),
+		SetStatus("active"),
+		Comment("From the nightly build"),
+	})
+	if err != nil {
+		t.Fatalf("Client.Edit returned error: %v", err)
+	}
+
+	if result.Object.PHID != "PHID-PSTE-1" {
+		t.Errorf("Client.Edit returned object %+v, expected PHID-PSTE-1", result.Object)
+	}
+
+	if phids, expected := result.TransactionPHIDs(), []string{"PHID-XACT-PSTE-1", "PHID-XACT-PSTE-2"}; !reflect.DeepEqual(phids, expected) {
+		t.Errorf("EditResult.TransactionPHIDs() = %v, expected %v", phids, expected)
+	}
+}
+
+func TestClient_EditError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":null,"error_code":"ERR-CONDUIT-CORE","error_info":"Validation errors:\n  - Tasks must have a title."}`)
+	})
+
+	_, _, err := client.Edit(ctx, "maniphest.edit", "", []Transaction{SetDescription("No title")})
+	if cerr, ok := err.(*ConduitError); !ok || cerr.Method != "maniphest.edit" {
+		t.Errorf("Client.Edit returned %v, expected a maniphest.edit *ConduitError", err)
+	}
+}
@@ -0,0 +1,216 @@
+package golph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// LocalCommit describes a commit in a local working copy, in the shape
+// Differential stores as the "local:commits" diff property.
+type LocalCommit struct {
+	Commit      string   `json:"commit"`
+	Tree        string   `json:"tree"`
+	Parents     []string `json:"parents"`
+	Time        int64    `json:"time"`
+	Author      string   `json:"author"`
+	AuthorEmail string   `json:"authorEmail"`
+	Summary     string   `json:"summary"`
+	Message     string   `json:"message"`
+}
+
+// GitError is returned when a git command run against a local working copy fails.
+type GitError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *GitError) Error() string {
+	return fmt.Sprintf("git %s: %v: %s", strings.Join(e.Args, " "), e.Err, strings.TrimSpace(e.Stderr))
+}
+
+// runGit runs git with args inside dir and returns what it wrote to stdout. The
+// git process is killed if ctx is done before it exits.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitInput(ctx, dir, "", args...)
+}
+
+// runGitInput is runGit with input fed to the command's stdin.
+func runGitInput(ctx context.Context, dir, input string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), &GitError{Args: args, Stderr: stderr.String(), Err: err}
+	}
+
+	return stdout.String(), nil
+}
+
+// checkGitRevisions returns an error for any of revs that git would read as
+// an option rather than a revision, like "--output=file".
+func checkGitRevisions(revs ...string) error {
+	for _, rev := range revs {
+		if strings.HasPrefix(rev, "-") {
+			return fmt.Errorf("%q is not a valid git revision", rev)
+		}
+	}
+	return nil
+}
+
+// GitDiff returns the unified diff between base and head in the git repository
+// at repoPath, generated the way arcanist does so Differential can show full
+// file context. An empty head diffs base against the working tree.
+func GitDiff(ctx context.Context, repoPath, base, head string) (string, error) {
+	if base == "" {
+		return "", errors.New("GitDiff needs a base to diff against")
+	}
+	if err := checkGitRevisions(base, head); err != nil {
+		return "", err
+	}
+
+	args := []string{"diff", "--no-ext-diff", "--no-textconv", "--no-color", "-M", "-C", "--full-index", "-U32767", base}
+	if head != "" {
+		args = append(args, head)
+	}
+	args = append(args, "--")
+
+	return runGit(ctx, repoPath, args...)
+}
+
+// GitCommits returns the commits reachable from head but not base in the git
+// repository at repoPath, keyed by hash.
+func GitCommits(ctx context.Context, repoPath, base, head string) (map[string]LocalCommit, error) {
+	if base == "" {
+		return nil, errors.New("GitCommits needs a base to list commits from")
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+	if err := checkGitRevisions(base, head); err != nil {
+		return nil, err
+	}
+
+	// Fields are separated by NUL and commits by a record separator, since
+	// commit messages can contain anything else.
+	out, err := runGit(ctx, repoPath, "log", "--format=%H%x00%T%x00%P%x00%at%x00%an%x00%ae%x00%s%x00%B%x1e", base+".."+head, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	commits := map[string]LocalCommit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, "\x00", 8)
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("unexpected git log output %q", record)
+		}
+
+		at, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		commits[fields[0]] = LocalCommit{
+			Commit:      fields[0],
+			Tree:        fields[1],
+			Parents:     strings.Fields(fields[2]),
+			Time:        at,
+			Author:      fields[4],
+			AuthorEmail: fields[5],
+			Summary:     fields[6],
+			Message:     strings.TrimSpace(fields[7]),
+		}
+	}
+
+	return commits, nil
+}
+
+// GitMergeBase returns the best common ancestor of a and b in the git
+// repository at repoPath. An empty b stands for HEAD.
+func GitMergeBase(ctx context.Context, repoPath, a, b string) (string, error) {
+	if b == "" {
+		b = "HEAD"
+	}
+	if err := checkGitRevisions(a, b); err != nil {
+		return "", err
+	}
+
+	out, err := runGit(ctx, repoPath, "merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// PatchConflictError is returned when a patch only partially applied to a
+// local working copy.
+type PatchConflictError struct {
+	Files []string
+}
+
+func (e *PatchConflictError) Error() string {
+	return fmt.Sprintf("patch conflicts in %s", strings.Join(e.Files, ", "))
+}
+
+// GitApply creates branch from base (the current HEAD when empty) in the git
+// repository at repoPath and applies patch to it, staging the result. Files
+// that could not be merged cleanly are reported through a *PatchConflictError.
+func GitApply(ctx context.Context, repoPath, branch, base, patch string) error {
+	if err := checkGitRevisions(branch, base); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, repoPath, "check-ref-format", "--branch", branch); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", branch)
+	}
+
+	args := []string{"checkout", "-q", "-b", branch}
+	if base != "" {
+		args = append(args, base)
+	}
+	args = append(args, "--")
+
+	if _, err := runGit(ctx, repoPath, args...); err != nil {
+		return err
+	}
+
+	_, applyErr := runGitInput(ctx, repoPath, patch, "apply", "--index", "--3way", "--whitespace=nowarn", "-")
+	if applyErr == nil {
+		return nil
+	}
+
+	out, err := runGit(ctx, repoPath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return err
+	}
+
+	conflicts := strings.Fields(out)
+	if len(conflicts) == 0 {
+		return applyErr
+	}
+
+	return &PatchConflictError{Files: conflicts}
+}
+
+// gitHasCommit reports whether the repository at repoPath knows about commit.
+func gitHasCommit(ctx context.Context, repoPath, commit string) bool {
+	if checkGitRevisions(commit) != nil {
+		return false
+	}
+	_, err := runGit(ctx, repoPath, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
@@ -0,0 +1,150 @@
+package golph
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupGitRepo creates a throwaway repository with two commits and returns
+// its path along with the hashes of both commits.
+func setupGitRepo(t *testing.T) (string, string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "golph")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(args ...string) string {
+		out, err := runGit(ctx, dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out)
+	}
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "master")
+	git("config", "user.name", "Golph")
+	git("config", "user.email", "golph@example.com")
+	git("config", "commit.gpgsign", "false")
+
+	write("README", "hello\n")
+	git("add", "README")
+	git("commit", "-q", "-m", "Initial commit")
+	base := git("rev-parse", "HEAD")
+
+	write("README", "hello\nworld\n")
+	git("commit", "-q", "-a", "-m", "Say hello to the world", "-m", "With a longer body.")
+	head := git("rev-parse", "HEAD")
+
+	return dir, base, head
+}
+
+func TestGitDiff(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	diff, err := GitDiff(ctx, dir, base, head)
+	if err != nil {
+		t.Fatalf("GitDiff returned error: %v", err)
+	}
+
+	if !strings.Contains(diff, "diff --git a/README b/README") || !strings.Contains(diff, "+world") {
+		t.Errorf("GitDiff returned unexpected diff:\n%s", diff)
+	}
+}
+
+func TestGitDiffWithoutBase(t *testing.T) {
+	if _, err := GitDiff(ctx, ".", "", "HEAD"); err == nil || err.Error() != "GitDiff needs a base to diff against" {
+		t.Errorf("GitDiff returned %v, expected an error asking for a base", err)
+	}
+}
+
+func TestGitMergeBase(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := runGit(ctx, dir, "branch", "upstream", base); err != nil {
+		t.Fatal(err)
+	}
+
+	mergeBase, err := GitMergeBase(ctx, dir, "upstream", "")
+	if err != nil {
+		t.Fatalf("GitMergeBase returned error: %v", err)
+	}
+	if mergeBase != base {
+		t.Errorf("GitMergeBase returned %q, expected %q (not %q)", mergeBase, base, head)
+	}
+}
+
+func TestGitCommits(t *testing.T) {
+	dir, base, head := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	commits, err := GitCommits(ctx, dir, base, head)
+	if err != nil {
+		t.Fatalf("GitCommits returned error: %v", err)
+	}
+
+	commit, ok := commits[head]
+	if len(commits) != 1 || !ok {
+		t.Fatalf("GitCommits returned %+v, expected only %s", commits, head)
+	}
+
+	if commit.Summary != "Say hello to the world" || commit.Message != "Say hello to the world\n\nWith a longer body." {
+		t.Errorf("GitCommits returned summary %q and message %q", commit.Summary, commit.Message)
+	}
+
+	if len(commit.Parents) != 1 || commit.Parents[0] != base || commit.AuthorEmail != "golph@example.com" {
+		t.Errorf("GitCommits returned %+v", commit)
+	}
+}
+
+func TestGitOptionLikeRevisions(t *testing.T) {
+	dir, base, _ := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "written")
+
+	if _, err := GitDiff(ctx, dir, base, "--output="+output); err == nil {
+		t.Errorf("GitDiff took a head of --output, expected an error")
+	}
+	if _, err := GitCommits(ctx, dir, "--output="+output, ""); err == nil {
+		t.Errorf("GitCommits took a base of --output, expected an error")
+	}
+	if _, err := GitMergeBase(ctx, dir, "--octopus", ""); err == nil {
+		t.Errorf("GitMergeBase took --octopus, expected an error")
+	}
+	if err := GitApply(ctx, dir, "-f", "", ""); err == nil {
+		t.Errorf("GitApply took a branch of -f, expected an error")
+	}
+	if err := GitApply(ctx, dir, "bad..name", "", ""); err == nil {
+		t.Errorf("GitApply took a branch of bad..name, expected an error")
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("git wrote to %s", output)
+	}
+}
+
+func TestGitError(t *testing.T) {
+	dir, _, _ := setupGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	_, err := GitDiff(ctx, dir, "no-such-ref", "")
+	if _, ok := err.(*GitError); !ok {
+		t.Errorf("GitDiff returned %#v, expected a *GitError", err)
+	}
+}
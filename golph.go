@@ -2,6 +2,7 @@ package golph
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -39,11 +41,24 @@ type Client struct {
 	UserAgent string
 
 	// Conduit connections, see https://secure.phabricator.com/conduit/
-	Projects ProjectsService
-	Tasks    TasksService
-	Users    UsersService
-
-	// Optional function called after every successful request made to the Phabricator APIs
+	Differential DifferentialService
+	Edges        EdgesService
+	Harbormaster HarbormasterService
+	PHIDs        PHIDsService
+	Projects     ProjectsService
+	Repositories RepositoriesService
+	Tasks        TasksService
+	Transactions TransactionsService
+	Users        UsersService
+
+	// Optional policy for retrying requests that fail transiently. Nil never retries.
+	RetryPolicy *RetryPolicy
+
+	// Optional limiter pacing the requests of every service. Nil sends them as they come.
+	RateLimiter *RateLimiter
+
+	// Optional function called after every successful request made to the Phabricator APIs,
+	// including each attempt of a retried request
 	onRequestCompleted RequestCompletionCallback
 }
 
@@ -56,11 +71,17 @@ type ListOptions struct {
 	// For paginated result sets, page of results to retrieve.
 	Page int `url:"page,omitempty"`
 
+	// Offset skips that many results. Limit is the most results a List call
+	// returns, fetched in pages of up to Limit.
 	Offset int `url:"offset,omitempty"`
 	Limit  int `url:"limit,omitempty"`
 
 	// For paginated result sets, the number of results to include per page.
 	PerPage int `url:"per_page,omitempty"`
+
+	// The most results a List call will page through. With neither Limit nor
+	// MaxResults set, it fetches them all.
+	MaxResults int `url:"-"`
 }
 
 // Response is a DigitalOcean response. This wraps the standard http.Response returned from DigitalOcean.
@@ -80,6 +101,41 @@ type ErrorResponse struct {
 	Message string
 }
 
+// Conduit error codes that the Is* helpers recognize.
+const (
+	ErrCodeInvalidAuth      = "ERR-INVALID-AUTH"
+	ErrCodeInvalidSession   = "ERR-INVALID-SESSION"
+	ErrCodeInvalidParameter = "ERR-INVALID-PARAMETER"
+	ErrCodeConduitCore      = "ERR-CONDUIT-CORE"
+	ErrCodeNotFound         = "ERR-NOT-FOUND"
+	ErrCodeRateLimit        = "ERR-RATE-LIMIT"
+)
+
+// notFoundCodes are the error codes the older Conduit methods use when the
+// requested object does not exist.
+var notFoundCodes = map[string]bool{
+	ErrCodeNotFound:    true,
+	"ERR_NOT_FOUND":    true,
+	"ERR_BAD_TASK":     true,
+	"ERR_BAD_REVISION": true,
+	"ERR_BAD_DIFF":     true,
+	"ERR_BAD_PHID":     true,
+}
+
+// A ConduitError reports a Conduit call that Phabricator answered with an error
+// code. Conduit replies to these with HTTP 200, so they are picked out of the
+// error_code and error_info fields of the response envelope.
+type ConduitError struct {
+	// HTTP response that carried this error
+	Response *http.Response
+
+	// Conduit method that was called, like maniphest.info
+	Method string
+
+	Code string
+	Info string
+}
+
 func addOptions(s string, opt interface{}) (string, error) {
 	v := reflect.ValueOf(opt)
 
@@ -120,8 +176,14 @@ func NewClient(apiToken string, phabricatorUrl string, httpClient *http.Client)
 	}
 
 	c := &Client{client: httpClient, apiToken: apiToken, BaseURL: baseURL, UserAgent: userAgent}
+	c.Differential = &DifferentialServiceOp{client: c}
+	c.Edges = &EdgesServiceOp{client: c}
+	c.Harbormaster = &HarbormasterServiceOp{client: c}
+	c.PHIDs = &PHIDsServiceOp{client: c}
 	c.Projects = &ProjectsServiceOp{client: c}
+	c.Repositories = &RepositoriesServiceOp{client: c}
 	c.Tasks = &TasksServiceOp{client: c}
+	c.Transactions = &TransactionsServiceOp{client: c}
 	c.Users = &UsersServiceOp{client: c}
 
 	return c
@@ -129,14 +191,9 @@ func NewClient(apiToken string, phabricatorUrl string, httpClient *http.Client)
 
 // NewRequest creates an API request. A relative URL can be provided in urlStr, which will be resolved to the
 // BaseURL of the Client. Relative URLS should always be specified without a preceding slash. If specified, the
-// value pointed to by body is JSON encoded and included in as the request body.
-func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	rel, err := url.Parse(urlStr)
-	if err != nil {
-		return nil, err
-	}
-
-	u := c.BaseURL.ResolveReference(rel)
+// value pointed to by body is flattened into form fields (see structToValues) and included as the request body.
+// The request is bound to ctx, so cancelling ctx aborts it.
+func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
 	postForm := url.Values{}
 
 	if body != nil {
@@ -147,6 +204,55 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 		postForm.Set("api.token", c.apiToken)
 	}
 
+	return c.newFormRequest(ctx, method, urlStr, postForm)
+}
+
+// NewJSONRequest creates an API request like NewRequest, but sends body the way arcanist does: JSON encoded
+// in the params field, with the API token inside its __conduit__ envelope. Nested structs, maps and booleans
+// are sent faithfully, which the *.search and *.edit methods need. The json struct tags of body apply.
+func (c *Client) NewJSONRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	params := map[string]interface{}{}
+
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if string(encoded) != "null" {
+			// UseNumber keeps large integers such as epochs exact on the way back out.
+			dec := json.NewDecoder(bytes.NewReader(encoded))
+			dec.UseNumber()
+			if err := dec.Decode(&params); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	params["__conduit__"] = map[string]string{"token": c.apiToken}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	postForm := url.Values{}
+	postForm.Set("params", string(encoded))
+	postForm.Set("output", "json")
+	postForm.Set("__conduit__", "1")
+
+	return c.newFormRequest(ctx, method, urlStr, postForm)
+}
+
+// newFormRequest creates an API request sending postForm as the request body.
+func (c *Client) newFormRequest(ctx context.Context, method, urlStr string, postForm url.Values) (*http.Request, error) {
+	rel, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.BaseURL.ResolveReference(rel)
+
 	buf := strings.NewReader(postForm.Encode())
 
 	//fmt.Printf("Sending form to Phabricator: %+q\n", buf)
@@ -158,7 +264,7 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 	req.Header.Add("Accept", mediaType)
 	req.Header.Add("User-Agent", userAgent)
 
-	return req, nil
+	return req.WithContext(ctx), nil
 }
 
 /***** \/\/\/\/\/
@@ -181,8 +287,14 @@ func newResponse(r *http.Response) *Response {
 
 // Do sends an API request and returns the API response. The API response is JSON decoded and stored in the value
 // pointed to by v, or returned as an error if an API error has occurred. If v implements the io.Writer interface,
-// the raw response will be written to v, without attempting to decode it.
+// the raw response will be written to v, without attempting to decode it. Transient failures are retried
+// according to the client's RetryPolicy.
 func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
+	return c.doWithRetry(req, v, c.doLimited)
+}
+
+// do sends an API request once, as described by Do.
+func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -204,18 +316,25 @@ func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
 	if err != nil {
 		return response, err
 	}
-	/*
-		body, _ := ioutil.ReadAll(resp.Body)
-		fmt.Println(string(body))
-	*/
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+
+	err = checkConduitError(resp, body)
+	if err != nil {
+		return response, err
+	}
+
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
-			_, err := io.Copy(w, resp.Body)
+			_, err := w.Write(body)
 			if err != nil {
 				return nil, err
 			}
 		} else {
-			err := json.NewDecoder(resp.Body).Decode(v)
+			err := json.Unmarshal(body, v)
 			if err != nil {
 				return nil, err
 			}
@@ -229,6 +348,86 @@ func (r *ErrorResponse) Error() string {
 		r.Response.Request.Method, r.Response.Request.URL, r.Response.StatusCode, r.Message)
 }
 
+func (r *ConduitError) Error() string {
+	return fmt.Sprintf("%v: %v %v", r.Method, r.Code, r.Info)
+}
+
+// checkConduitError returns a *ConduitError if body is a Conduit envelope
+// carrying an error code. Bodies that are not JSON are left to the caller.
+func checkConduitError(r *http.Response, body []byte) error {
+	var envelope struct {
+		ErrorCode string `json:"error_code"`
+		ErrorInfo string `json:"error_info"`
+	}
+
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.ErrorCode == "" {
+		return nil
+	}
+
+	return &ConduitError{
+		Response: r,
+		Method:   conduitMethod(r.Request),
+		Code:     envelope.ErrorCode,
+		Info:     envelope.ErrorInfo,
+	}
+}
+
+// conduitErrorCode returns the Conduit error code carried by err, if any.
+func conduitErrorCode(err error) string {
+	if cerr, ok := err.(*ConduitError); ok {
+		return cerr.Code
+	}
+	return ""
+}
+
+// IsInvalidAuth reports whether err is a Conduit error for a missing, expired
+// or invalid API token.
+func IsInvalidAuth(err error) bool {
+	code := conduitErrorCode(err)
+	return code == ErrCodeInvalidAuth || code == ErrCodeInvalidSession
+}
+
+// IsNotFound reports whether err is a Conduit error for an object that does not
+// exist.
+func IsNotFound(err error) bool {
+	return notFoundCodes[conduitErrorCode(err)]
+}
+
+// notFoundError returns the error for a read that came back empty, which
+// IsNotFound recognizes like Phabricator's own not found errors.
+func notFoundError(resp *Response, format string, a ...interface{}) error {
+	err := &ConduitError{Code: ErrCodeNotFound, Info: fmt.Sprintf(format, a...)}
+	if resp != nil && resp.Response != nil {
+		err.Response = resp.Response
+		err.Method = conduitMethod(resp.Request)
+	}
+	return err
+}
+
+// IsInvalidParameter reports whether err is a Conduit error for a parameter
+// that was missing or had the wrong type.
+func IsInvalidParameter(err error) bool {
+	code := conduitErrorCode(err)
+	return code == ErrCodeInvalidParameter || code == "ERR_INVALID_PARAMETER"
+}
+
+// IsRateLimited reports whether err is Phabricator turning a request away for
+// being over its rate limit, either with HTTP 429 or as a Conduit error.
+func IsRateLimited(err error) bool {
+	switch err := err.(type) {
+	case *ErrorResponse:
+		return err.Response.StatusCode == http.StatusTooManyRequests
+	case *ConduitError:
+		if err.Code == ErrCodeRateLimit {
+			return true
+		}
+		// Action limits are reported as core errors, told apart by their message.
+		info := strings.ToLower(err.Info)
+		return err.Code == ErrCodeConduitCore && (strings.Contains(info, "too many") || strings.Contains(info, "rate limit"))
+	}
+	return false
+}
+
 // CheckResponse checks the API response for errors, and returns them if present. A response is considered an
 // error if it has a status code outside the 200 range. API error responses are expected to have either no response
 // body, or a JSON response body that maps to ErrorResponse. Any other response body will be silently ignored.
@@ -240,10 +439,9 @@ func CheckResponse(r *http.Response) error {
 	errorResponse := &ErrorResponse{Response: r}
 	data, err := ioutil.ReadAll(r.Body)
 	if err == nil && len(data) > 0 {
-		err := json.Unmarshal(data, errorResponse)
-		if err != nil {
-			return err
-		}
+		// Proxies in front of Phabricator answer with HTML or plain text, which
+		// is not worth more than the status code.
+		_ = json.Unmarshal(data, errorResponse)
 	}
 
 	return errorResponse
@@ -266,6 +464,14 @@ func Int(v int) *int {
 	return p
 }
 
+// Float64 is a helper routine that allocates a new float64 value
+// to store v and returns a pointer to it.
+func Float64(v float64) *float64 {
+	p := new(float64)
+	*p = v
+	return p
+}
+
 // Bool is a helper routine that allocates a new bool value
 // to store v and returns a pointer to it.
 func Bool(v bool) *bool {
@@ -274,6 +480,12 @@ func Bool(v bool) *bool {
 	return p
 }
 
+// isEmptyJSONArray reports whether data is an empty JSON list, which is how PHP
+// encodes an empty associative array.
+func isEmptyJSONArray(data []byte) bool {
+	return string(bytes.Join(bytes.Fields(data), nil)) == "[]"
+}
+
 // StreamToString converts a reader to a string
 func StreamToString(stream io.Reader) string {
 	buf := new(bytes.Buffer)
@@ -283,45 +495,117 @@ func StreamToString(stream io.Reader) string {
 
 func structToValues(i interface{}) (values url.Values) {
 	values = url.Values{}
-	iVal := reflect.ValueOf(i).Elem()
-	typ := iVal.Type()
-	for i := 0; i < iVal.NumField(); i++ {
-		f := iVal.Field(i)
-		// You ca use tags here...
-		// tag := typ.Field(i).Tag.Get("tagname")
-		// Convert each type into a string for the url.Values string map
-
-		fieldName := typ.Field(i).Name
-		if typ.Field(i).Tag.Get("form") != "" {
-			fieldName = typ.Field(i).Tag.Get("form")
+	addFormValue(values, "", reflect.ValueOf(i))
+	return
+}
+
+// addFormValue encodes v into values under key. Nested structs, slices and maps
+// use the PHP style bracket notation that Conduit understands, so a field tagged
+// `form:"constraints"` holding a struct with a `form:"usernames"` slice ends up
+// as constraints[usernames][0]=...
+func addFormValue(values url.Values, key string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
 		}
+		addFormValue(values, key, v.Elem())
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			fieldName, omitEmpty := formFieldName(field)
+			if fieldName == "-" {
+				continue
+			}
+
+			f := v.Field(i)
+			if omitEmpty && isEmptyValue(f) {
+				continue
+			}
 
-		skipSet := false
-
-		var v string
-		switch f.Interface().(type) {
-		case int, int8, int16, int32, int64:
-			v = strconv.FormatInt(f.Int(), 10)
-		case uint, uint8, uint16, uint32, uint64:
-			v = strconv.FormatUint(f.Uint(), 10)
-		case float32:
-			v = strconv.FormatFloat(f.Float(), 'f', 4, 32)
-		case float64:
-			v = strconv.FormatFloat(f.Float(), 'f', 4, 64)
-		case []byte:
-			v = string(f.Bytes())
-		case string:
-			v = f.String()
-		case []string:
-			for idx := 0; idx < f.Len(); idx += 1 {
-				values.Set(fmt.Sprintf("%s[%d]", fieldName, idx), f.Index(idx).String())
+			if key != "" {
+				fieldName = fmt.Sprintf("%s[%s]", key, fieldName)
 			}
-			skipSet = true
+			addFormValue(values, fieldName, f)
 		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			values.Set(key, string(v.Bytes()))
+			return
+		}
+		for idx := 0; idx < v.Len(); idx += 1 {
+			addFormValue(values, fmt.Sprintf("%s[%d]", key, idx), v.Index(idx))
+		}
+	case reflect.Map:
+		mapKeys := map[string]reflect.Value{}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			name := fmt.Sprint(k.Interface())
+			mapKeys[name] = k
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			addFormValue(values, fmt.Sprintf("%s[%s]", key, k), v.MapIndex(mapKeys[k]))
+		}
+	case reflect.Bool:
+		values.Set(key, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Set(key, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		values.Set(key, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32:
+		values.Set(key, strconv.FormatFloat(v.Float(), 'f', 4, 32))
+	case reflect.Float64:
+		values.Set(key, strconv.FormatFloat(v.Float(), 'f', 4, 64))
+	case reflect.String:
+		values.Set(key, v.String())
+	default:
+		values.Set(key, "")
+	}
+}
+
+// formFieldName returns the form name of a struct field, taken from the form
+// tag when present, and whether the field should be skipped when empty.
+func formFieldName(field reflect.StructField) (string, bool) {
+	name := field.Name
+	tag := field.Tag.Get("form")
+	if tag == "" {
+		return name, false
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
 
-		if skipSet == false {
-			values.Set(fieldName, v)
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
 		}
 	}
-	return
+	return name, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
 }
@@ -1,7 +1,9 @@
 package golph
 
 import (
-	//"encoding/json"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -19,6 +21,8 @@ var (
 	client *Client
 
 	server *httptest.Server
+
+	ctx = context.TODO()
 )
 
 func setup() {
@@ -58,6 +62,43 @@ func testFormValues(t *testing.T, r *http.Request, values values) {
 	}
 }
 
+// conduitParams decodes the JSON params of a request made with NewJSONRequest,
+// checking the __conduit__ envelope and dropping it from the result.
+func conduitParams(t *testing.T, r *http.Request) map[string]interface{} {
+	if r.PostFormValue("output") != "json" || r.PostFormValue("__conduit__") != "1" {
+		t.Errorf("Request is not JSON encoded: %v", r.PostForm)
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(r.PostFormValue("params")), &params); err != nil {
+		t.Fatalf("Request params are not JSON: %v", err)
+	}
+
+	envelope, _ := params["__conduit__"].(map[string]interface{})
+	if envelope["token"] != "api token goes here" {
+		t.Errorf("Request __conduit__ = %v, expected the API token", params["__conduit__"])
+	}
+	delete(params, "__conduit__")
+
+	return params
+}
+
+// testJSONParams checks the JSON params of a request made with NewJSONRequest
+// against the expected JSON document.
+func testJSONParams(t *testing.T, r *http.Request, expected string) {
+	params := conduitParams(t, r)
+
+	want := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("Expected params are not JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(params, want) {
+		got, _ := json.Marshal(params)
+		t.Errorf("Request params = %s, expected %s", got, expected)
+	}
+}
+
 func testURLParseError(t *testing.T, err error) {
 	if err == nil {
 		t.Errorf("Expected error to be returned")
@@ -84,7 +125,7 @@ func TestNewRequest(t *testing.T) {
 
 	inURL, outURL := "/foo", defaultBaseURL+"foo"
 	inBody := &ProjectCreateRequest{Name: "l"}
-	req, _ := c.NewRequest("POST", inURL, inBody)
+	req, _ := c.NewRequest(ctx, "POST", inURL, inBody)
 
 	// test relative URL was expanded
 	if req.URL.String() != outURL {
@@ -102,9 +143,49 @@ func TestNewRequest(t *testing.T) {
 	}
 }
 
+func TestNewJSONRequest(t *testing.T) {
+	apiToken := "api-token"
+	c := NewClient(apiToken, "", nil)
+
+	type constraints struct {
+		Names   []string `json:"names"`
+		IsAdmin bool     `json:"isAdmin"`
+	}
+	inBody := &struct {
+		Constraints constraints       `json:"constraints"`
+		Quoted      string            `json:"quoted"`
+		Map         map[string]string `json:"map"`
+	}{
+		Constraints: constraints{Names: []string{"alice"}, IsAdmin: true},
+		Quoted:      `a "quoted" name`,
+		Map:         map[string]string{"a": "b"},
+	}
+
+	req, err := c.NewJSONRequest(ctx, "POST", "/foo", inBody)
+	if err != nil {
+		t.Fatalf("NewJSONRequest returned error: %v", err)
+	}
+
+	if req.PostFormValue("api.token") != "" {
+		t.Errorf("req.PostFormValue(api.token) = %v, expected the token inside params", req.PostFormValue("api.token"))
+	}
+
+	if req.PostFormValue("output") != "json" || req.PostFormValue("__conduit__") != "1" {
+		t.Errorf("NewJSONRequest form = %v, expected output=json and __conduit__=1", req.PostForm)
+	}
+
+	expected := `{"constraints":{"names":["alice"],"isAdmin":true},"quoted":"a \"quoted\" name","map":{"a":"b"},"__conduit__":{"token":"api-token"}}`
+	var got, want interface{}
+	json.Unmarshal([]byte(req.PostFormValue("params")), &got)
+	json.Unmarshal([]byte(expected), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewJSONRequest params = %v, expected %v", req.PostFormValue("params"), expected)
+	}
+}
+
 func TestNewRequest_badURL(t *testing.T) {
 	c := NewClient("api-token", "", nil)
-	_, err := c.NewRequest("GET", ":", nil)
+	_, err := c.NewRequest(ctx, "GET", ":", nil)
 	testURLParseError(t, err)
 }
 
@@ -123,7 +204,7 @@ func TestDo(t *testing.T) {
 		fmt.Fprint(w, `{"A":"a"}`)
 	})
 
-	req, _ := client.NewRequest("GET", "/", nil)
+	req, _ := client.NewRequest(ctx, "GET", "/", nil)
 	body := new(foo)
 	_, err := client.Do(req, body)
 	if err != nil {
@@ -144,7 +225,7 @@ func TestDo_httpError(t *testing.T) {
 		http.Error(w, "Bad Request", 400)
 	})
 
-	req, _ := client.NewRequest("GET", "/", nil)
+	req, _ := client.NewRequest(ctx, "GET", "/", nil)
 	_, err := client.Do(req, nil)
 
 	if err == nil {
@@ -162,7 +243,7 @@ func TestDo_redirectLoop(t *testing.T) {
 		http.Redirect(w, r, "/", http.StatusFound)
 	})
 
-	req, _ := client.NewRequest("GET", "/", nil)
+	req, _ := client.NewRequest(ctx, "GET", "/", nil)
 	_, err := client.Do(req, nil)
 
 	if err == nil {
@@ -173,6 +254,25 @@ func TestDo_redirectLoop(t *testing.T) {
 	}
 }
 
+func TestDo_contextCancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the cancelled request not to be sent")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, _ := client.NewRequest(ctx, "GET", "/", nil)
+	_, err := client.Do(req, nil)
+
+	if err, ok := err.(*url.Error); !ok || err.Err != context.Canceled {
+		t.Errorf("Expected a cancelled URL error; got %#v.", err)
+	}
+}
+
 func TestCheckResponse(t *testing.T) {
 	res := &http.Response{
 		Request:    &http.Request{},
@@ -225,6 +325,61 @@ func TestErrorResponse_Error(t *testing.T) {
 	}
 }
 
+func TestDo_conduitError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":null,"error_code":"ERR-INVALID-AUTH","error_info":"API token \"api-xyz\" has the wrong length."}`)
+	})
+
+	req, _ := client.NewRequest(ctx, "POST", "api/user.whoami", nil)
+	_, err := client.Do(req, new(SingleUserResponse))
+
+	cerr, ok := err.(*ConduitError)
+	if !ok {
+		t.Fatalf("Expected a *ConduitError; got %#v", err)
+	}
+
+	if cerr.Method != "user.whoami" || cerr.Code != "ERR-INVALID-AUTH" || cerr.Info != `API token "api-xyz" has the wrong length.` {
+		t.Errorf("ConduitError = %+v", cerr)
+	}
+
+	if !IsInvalidAuth(err) {
+		t.Errorf("IsInvalidAuth(%v) = false, expected true", err)
+	}
+	if IsNotFound(err) || IsInvalidParameter(err) {
+		t.Errorf("Expected %v to only be an auth error", err)
+	}
+}
+
+func TestConduitError_helpers(t *testing.T) {
+	cases := []struct {
+		code                                string
+		invalidAuth, notFound, invalidParam bool
+	}{
+		{"ERR-INVALID-AUTH", true, false, false},
+		{"ERR-INVALID-SESSION", true, false, false},
+		{"ERR_BAD_TASK", false, true, false},
+		{"ERR-NOT-FOUND", false, true, false},
+		{"ERR-INVALID-PARAMETER", false, false, true},
+		{"ERR-CONDUIT-CORE", false, false, false},
+	}
+
+	for _, c := range cases {
+		err := &ConduitError{Method: "maniphest.info", Code: c.code}
+		if IsInvalidAuth(err) != c.invalidAuth || IsNotFound(err) != c.notFound || IsInvalidParameter(err) != c.invalidParam {
+			t.Errorf("Helpers for %s = %v/%v/%v, expected %v/%v/%v", c.code,
+				IsInvalidAuth(err), IsNotFound(err), IsInvalidParameter(err),
+				c.invalidAuth, c.notFound, c.invalidParam)
+		}
+	}
+
+	if IsNotFound(errors.New("ERR_BAD_TASK")) {
+		t.Errorf("Expected plain errors not to be Conduit errors")
+	}
+}
+
 func TestDo_completion_callback(t *testing.T) {
 	setup()
 	defer teardown()
@@ -240,7 +395,7 @@ func TestDo_completion_callback(t *testing.T) {
 		fmt.Fprint(w, `{"A":"a"}`)
 	})
 
-	req, _ := client.NewRequest("GET", "/", nil)
+	req, _ := client.NewRequest(ctx, "GET", "/", nil)
 	body := new(foo)
 	var completedReq *http.Request
 	var completedResp string
@@ -0,0 +1,620 @@
+package golph
+
+import (
+	"context"
+	"errors"
+)
+
+const harbormasterSendMessagePath = "api/harbormaster.sendmessage"
+const harbormasterCreateArtifactPath = "api/harbormaster.createartifact"
+const harbormasterBuildSearchPath = "api/harbormaster.build.search"
+const harbormasterBuildableSearchPath = "api/harbormaster.buildable.search"
+const harbormasterTargetSearchPath = "api/harbormaster.target.search"
+const harbormasterLogSearchPath = "api/harbormaster.log.search"
+
+// HarbormasterService is an interface for reporting builds run outside of
+// Phabricator into Harbormaster, and for reading builds back.
+// See: https://secure.phabricator.com/conduit/ (and search for harbormaster)
+type HarbormasterService interface {
+	SendMessage(context.Context, *BuildMessage) (*Response, error)
+	CreateArtifact(context.Context, *ArtifactCreateRequest) (*Artifact, *Response, error)
+	SearchBuilds(context.Context, *BuildSearchRequest) ([]Build, *Response, error)
+	SearchBuildables(context.Context, *BuildableSearchRequest) ([]Buildable, *Response, error)
+	SearchTargets(context.Context, *BuildTargetSearchRequest) ([]BuildTarget, *Response, error)
+	SearchLogs(context.Context, *BuildLogSearchRequest) ([]BuildLog, *Response, error)
+}
+
+// HarbormasterServiceOp handles communication with the conduit methods
+type HarbormasterServiceOp struct {
+	client *Client
+}
+
+var _ HarbormasterService = &HarbormasterServiceOp{}
+
+// Message types a build target can be sent. Work reports progress without
+// finishing the target; pass and fail finish it.
+const (
+	BuildMessageWork = "work"
+	BuildMessagePass = "pass"
+	BuildMessageFail = "fail"
+)
+
+// Results a UnitResult can have.
+const (
+	UnitResultPass    = "pass"
+	UnitResultFail    = "fail"
+	UnitResultSkip    = "skip"
+	UnitResultBroken  = "broken"
+	UnitResultUnsound = "unsound"
+)
+
+// Severities a LintResult can have.
+const (
+	LintSeverityAdvice   = "advice"
+	LintSeverityAutofix  = "autofix"
+	LintSeverityWarning  = "warning"
+	LintSeverityError    = "error"
+	LintSeverityDisabled = "disabled"
+)
+
+// UnitResult is the result of a single test, as arc unit reports it.
+type UnitResult struct {
+	Name      string `json:"name"`
+	Result    string `json:"result"`
+	Namespace string `json:"namespace,omitempty"`
+	Engine    string `json:"engine,omitempty"`
+
+	// Duration of the test in seconds
+	Duration float64 `json:"duration,omitempty"`
+
+	Path string `json:"path,omitempty"`
+
+	// Coverage maps paths to a string with a character per line: N for not
+	// executable, C for covered, U for uncovered.
+	Coverage map[string]string `json:"coverage,omitempty"`
+
+	Details string `json:"details,omitempty"`
+
+	// Format of Details, "text" or "remarkup"
+	Format string `json:"format,omitempty"`
+}
+
+// LintResult is a single lint message, as arc lint reports it.
+type LintResult struct {
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	Severity    string `json:"severity"`
+	Path        string `json:"path"`
+	Line        int    `json:"line,omitempty"`
+	Char        int    `json:"char,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// BuildMessage represents a request to harbormaster.sendmessage. Receiver is
+// the PHID of the build target, or of a buildable for builds run without a
+// build plan.
+type BuildMessage struct {
+	Receiver string       `json:"receiver"`
+	Type     string       `json:"type"`
+	Unit     []UnitResult `json:"unit,omitempty"`
+	Lint     []LintResult `json:"lint,omitempty"`
+}
+
+// Artifact types createartifact accepts.
+const (
+	ArtifactTypeURI  = "uri"
+	ArtifactTypeFile = "file"
+)
+
+// ArtifactCreateRequest represents a request to harbormaster.createartifact.
+type ArtifactCreateRequest struct {
+	BuildTargetPHID string      `json:"buildTargetPHID"`
+	ArtifactKey     string      `json:"artifactKey"`
+	ArtifactType    string      `json:"artifactType"`
+	ArtifactData    interface{} `json:"artifactData"`
+}
+
+// URIArtifact links a build target to a URI, such as the page of the build in
+// your CI. External links open outside of Phabricator.
+func URIArtifact(targetPHID, key, uri, name string, external bool) *ArtifactCreateRequest {
+	return &ArtifactCreateRequest{
+		BuildTargetPHID: targetPHID,
+		ArtifactKey:     key,
+		ArtifactType:    ArtifactTypeURI,
+		ArtifactData: map[string]interface{}{
+			"uri":         uri,
+			"name":        name,
+			"ui.external": external,
+		},
+	}
+}
+
+// FileArtifact attaches an uploaded file to a build target.
+func FileArtifact(targetPHID, key, filePHID string) *ArtifactCreateRequest {
+	return &ArtifactCreateRequest{
+		BuildTargetPHID: targetPHID,
+		ArtifactKey:     key,
+		ArtifactType:    ArtifactTypeFile,
+		ArtifactData:    map[string]interface{}{"filePHID": filePHID},
+	}
+}
+
+// Artifact is something a build target produced.
+type Artifact struct {
+	ID              int        `json:"id"`
+	PHID            string     `json:"phid"`
+	BuildTargetPHID string     `json:"buildTargetPHID"`
+	ArtifactType    string     `json:"artifactType"`
+	ArtifactKey     string     `json:"artifactKey"`
+	IsReleased      bool       `json:"isReleased"`
+	DateCreated     *Timestamp `json:"dateCreated,omitempty"`
+	DateModified    *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f Artifact) String() string {
+	return Stringify(f)
+}
+
+// Build is a run of a build plan against a buildable.
+type Build struct {
+	ID            int        `json:"id"`
+	PHID          string     `json:"phid"`
+	Name          string     `json:"name"`
+	BuildablePHID string     `json:"buildablePHID"`
+	BuildPlanPHID string     `json:"buildPlanPHID"`
+	InitiatorPHID string     `json:"initiatorPHID"`
+	Status        string     `json:"status"`
+	StatusName    string     `json:"statusName"`
+	DateCreated   *Timestamp `json:"dateCreated,omitempty"`
+	DateModified  *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f Build) String() string {
+	return Stringify(f)
+}
+
+// Buildable is something that gets built, like a diff or a commit.
+type Buildable struct {
+	ID            int        `json:"id"`
+	PHID          string     `json:"phid"`
+	ObjectPHID    string     `json:"objectPHID"`
+	ContainerPHID string     `json:"containerPHID"`
+	Status        string     `json:"status"`
+	IsManual      bool       `json:"isManual"`
+	URI           string     `json:"uri"`
+	DateCreated   *Timestamp `json:"dateCreated,omitempty"`
+	DateModified  *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f Buildable) String() string {
+	return Stringify(f)
+}
+
+// BuildTarget is a single step of a build, which is what external builds
+// report into.
+type BuildTarget struct {
+	ID              int        `json:"id"`
+	PHID            string     `json:"phid"`
+	Name            string     `json:"name"`
+	BuildPHID       string     `json:"buildPHID"`
+	BuildStepPHID   string     `json:"buildStepPHID"`
+	Status          string     `json:"status"`
+	BuildGeneration int        `json:"buildGeneration"`
+	DateStarted     *Timestamp `json:"epochStarted,omitempty"`
+	DateCompleted   *Timestamp `json:"epochCompleted,omitempty"`
+	DateCreated     *Timestamp `json:"dateCreated,omitempty"`
+	DateModified    *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f BuildTarget) String() string {
+	return Stringify(f)
+}
+
+// BuildLog is the log of a build target.
+type BuildLog struct {
+	ID              int        `json:"id"`
+	PHID            string     `json:"phid"`
+	BuildTargetPHID string     `json:"buildTargetPHID"`
+	ByteLength      int64      `json:"byteLength"`
+	FilePHID        string     `json:"filePHID"`
+	DateCreated     *Timestamp `json:"dateCreated,omitempty"`
+	DateModified    *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f BuildLog) String() string {
+	return Stringify(f)
+}
+
+// BuildSearchConstraints narrows down the results of harbormaster.build.search.
+type BuildSearchConstraints struct {
+	IDs        []int    `json:"ids,omitempty"`
+	PHIDs      []string `json:"phids,omitempty"`
+	Plans      []string `json:"plans,omitempty"`
+	Buildables []string `json:"buildables,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"`
+	Initiators []string `json:"initiators,omitempty"`
+}
+
+// BuildSearchRequest represents a request to harbormaster.build.search.
+type BuildSearchRequest struct {
+	QueryKey    string                  `json:"queryKey,omitempty"`
+	Constraints *BuildSearchConstraints `json:"constraints,omitempty"`
+	Order       string                  `json:"order,omitempty"`
+	Before      string                  `json:"before,omitempty"`
+	After       string                  `json:"after,omitempty"`
+	Limit       int                     `json:"limit,omitempty"`
+}
+
+// BuildableSearchConstraints narrows down the results of
+// harbormaster.buildable.search.
+type BuildableSearchConstraints struct {
+	IDs            []int    `json:"ids,omitempty"`
+	PHIDs          []string `json:"phids,omitempty"`
+	ObjectPHIDs    []string `json:"objectPHIDs,omitempty"`
+	ContainerPHIDs []string `json:"containerPHIDs,omitempty"`
+	Statuses       []string `json:"statuses,omitempty"`
+	Manual         *bool    `json:"manual,omitempty"`
+}
+
+// BuildableSearchRequest represents a request to harbormaster.buildable.search.
+type BuildableSearchRequest struct {
+	QueryKey    string                      `json:"queryKey,omitempty"`
+	Constraints *BuildableSearchConstraints `json:"constraints,omitempty"`
+	Order       string                      `json:"order,omitempty"`
+	Before      string                      `json:"before,omitempty"`
+	After       string                      `json:"after,omitempty"`
+	Limit       int                         `json:"limit,omitempty"`
+}
+
+// BuildTargetSearchConstraints narrows down the results of
+// harbormaster.target.search.
+type BuildTargetSearchConstraints struct {
+	IDs        []int    `json:"ids,omitempty"`
+	PHIDs      []string `json:"phids,omitempty"`
+	BuildPHIDs []string `json:"buildPHIDs,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"`
+}
+
+// BuildTargetSearchRequest represents a request to harbormaster.target.search.
+type BuildTargetSearchRequest struct {
+	QueryKey    string                        `json:"queryKey,omitempty"`
+	Constraints *BuildTargetSearchConstraints `json:"constraints,omitempty"`
+	Order       string                        `json:"order,omitempty"`
+	Before      string                        `json:"before,omitempty"`
+	After       string                        `json:"after,omitempty"`
+	Limit       int                           `json:"limit,omitempty"`
+}
+
+// BuildLogSearchConstraints narrows down the results of harbormaster.log.search.
+type BuildLogSearchConstraints struct {
+	BuildTargetPHIDs []string `json:"buildTargetPHIDs,omitempty"`
+}
+
+// BuildLogSearchRequest represents a request to harbormaster.log.search.
+type BuildLogSearchRequest struct {
+	QueryKey    string                     `json:"queryKey,omitempty"`
+	Constraints *BuildLogSearchConstraints `json:"constraints,omitempty"`
+	Order       string                     `json:"order,omitempty"`
+	Before      string                     `json:"before,omitempty"`
+	After       string                     `json:"after,omitempty"`
+	Limit       int                        `json:"limit,omitempty"`
+}
+
+// harbormasterStatus is how the *.search methods report a status.
+type harbormasterStatus struct {
+	Value string `json:"value"`
+	Name  string `json:"name"`
+}
+
+type artifactData struct {
+	ID     int    `json:"id"`
+	PHID   string `json:"phid"`
+	Fields struct {
+		BuildTargetPHID string     `json:"buildTargetPHID"`
+		ArtifactType    string     `json:"artifactType"`
+		ArtifactKey     string     `json:"artifactKey"`
+		IsReleased      bool       `json:"isReleased"`
+		DateCreated     *Timestamp `json:"dateCreated"`
+		DateModified    *Timestamp `json:"dateModified"`
+	} `json:"fields"`
+}
+
+func (d artifactData) toArtifact() Artifact {
+	return Artifact{
+		ID:              d.ID,
+		PHID:            d.PHID,
+		BuildTargetPHID: d.Fields.BuildTargetPHID,
+		ArtifactType:    d.Fields.ArtifactType,
+		ArtifactKey:     d.Fields.ArtifactKey,
+		IsReleased:      d.Fields.IsReleased,
+		DateCreated:     d.Fields.DateCreated,
+		DateModified:    d.Fields.DateModified,
+	}
+}
+
+type buildSearchData struct {
+	ID     int    `json:"id"`
+	PHID   string `json:"phid"`
+	Fields struct {
+		Name          string             `json:"name"`
+		BuildablePHID string             `json:"buildablePHID"`
+		BuildPlanPHID string             `json:"buildPlanPHID"`
+		InitiatorPHID string             `json:"initiatorPHID"`
+		BuildStatus   harbormasterStatus `json:"buildStatus"`
+		DateCreated   *Timestamp         `json:"dateCreated"`
+		DateModified  *Timestamp         `json:"dateModified"`
+	} `json:"fields"`
+}
+
+func (d buildSearchData) toBuild() Build {
+	return Build{
+		ID:            d.ID,
+		PHID:          d.PHID,
+		Name:          d.Fields.Name,
+		BuildablePHID: d.Fields.BuildablePHID,
+		BuildPlanPHID: d.Fields.BuildPlanPHID,
+		InitiatorPHID: d.Fields.InitiatorPHID,
+		Status:        d.Fields.BuildStatus.Value,
+		StatusName:    d.Fields.BuildStatus.Name,
+		DateCreated:   d.Fields.DateCreated,
+		DateModified:  d.Fields.DateModified,
+	}
+}
+
+type buildableSearchData struct {
+	ID     int    `json:"id"`
+	PHID   string `json:"phid"`
+	Fields struct {
+		ObjectPHID      string             `json:"objectPHID"`
+		ContainerPHID   string             `json:"containerPHID"`
+		BuildableStatus harbormasterStatus `json:"buildableStatus"`
+		IsManual        bool               `json:"isManual"`
+		URI             string             `json:"uri"`
+		DateCreated     *Timestamp         `json:"dateCreated"`
+		DateModified    *Timestamp         `json:"dateModified"`
+	} `json:"fields"`
+}
+
+func (d buildableSearchData) toBuildable() Buildable {
+	return Buildable{
+		ID:            d.ID,
+		PHID:          d.PHID,
+		ObjectPHID:    d.Fields.ObjectPHID,
+		ContainerPHID: d.Fields.ContainerPHID,
+		Status:        d.Fields.BuildableStatus.Value,
+		IsManual:      d.Fields.IsManual,
+		URI:           d.Fields.URI,
+		DateCreated:   d.Fields.DateCreated,
+		DateModified:  d.Fields.DateModified,
+	}
+}
+
+type buildTargetSearchData struct {
+	ID     int    `json:"id"`
+	PHID   string `json:"phid"`
+	Fields struct {
+		Name            string             `json:"name"`
+		BuildPHID       string             `json:"buildPHID"`
+		BuildStepPHID   string             `json:"buildStepPHID"`
+		Status          harbormasterStatus `json:"status"`
+		BuildGeneration int                `json:"buildGeneration"`
+		EpochStarted    *Timestamp         `json:"epochStarted"`
+		EpochCompleted  *Timestamp         `json:"epochCompleted"`
+		DateCreated     *Timestamp         `json:"dateCreated"`
+		DateModified    *Timestamp         `json:"dateModified"`
+	} `json:"fields"`
+}
+
+func (d buildTargetSearchData) toBuildTarget() BuildTarget {
+	return BuildTarget{
+		ID:              d.ID,
+		PHID:            d.PHID,
+		Name:            d.Fields.Name,
+		BuildPHID:       d.Fields.BuildPHID,
+		BuildStepPHID:   d.Fields.BuildStepPHID,
+		Status:          d.Fields.Status.Value,
+		BuildGeneration: d.Fields.BuildGeneration,
+		DateStarted:     d.Fields.EpochStarted,
+		DateCompleted:   d.Fields.EpochCompleted,
+		DateCreated:     d.Fields.DateCreated,
+		DateModified:    d.Fields.DateModified,
+	}
+}
+
+type buildLogSearchData struct {
+	ID     int    `json:"id"`
+	PHID   string `json:"phid"`
+	Fields struct {
+		BuildTargetPHID string     `json:"buildTargetPHID"`
+		ByteLength      int64      `json:"byteLength"`
+		FilePHID        string     `json:"filePHID"`
+		DateCreated     *Timestamp `json:"dateCreated"`
+		DateModified    *Timestamp `json:"dateModified"`
+	} `json:"fields"`
+}
+
+func (d buildLogSearchData) toBuildLog() BuildLog {
+	return BuildLog{
+		ID:              d.ID,
+		PHID:            d.PHID,
+		BuildTargetPHID: d.Fields.BuildTargetPHID,
+		ByteLength:      d.Fields.ByteLength,
+		FilePHID:        d.Fields.FilePHID,
+		DateCreated:     d.Fields.DateCreated,
+		DateModified:    d.Fields.DateModified,
+	}
+}
+
+type ArtifactResult struct {
+	Data []artifactData `json:"data"`
+}
+
+type ArtifactResponse struct {
+	Result    ArtifactResult `json:"result"`
+	ErrorCode string         `json:"error_code,omitempty"`
+	ErrorInfo string         `json:"error_info,omitempty"`
+}
+
+type BuildSearchResult struct {
+	Data   []buildSearchData `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
+}
+
+type BuildSearchResponse struct {
+	Result    BuildSearchResult `json:"result"`
+	ErrorCode string            `json:"error_code,omitempty"`
+	ErrorInfo string            `json:"error_info,omitempty"`
+}
+
+type BuildableSearchResult struct {
+	Data   []buildableSearchData `json:"data"`
+	Cursor PhabricatorCursor     `json:"cursor"`
+}
+
+type BuildableSearchResponse struct {
+	Result    BuildableSearchResult `json:"result"`
+	ErrorCode string                `json:"error_code,omitempty"`
+	ErrorInfo string                `json:"error_info,omitempty"`
+}
+
+type BuildTargetSearchResult struct {
+	Data   []buildTargetSearchData `json:"data"`
+	Cursor PhabricatorCursor       `json:"cursor"`
+}
+
+type BuildTargetSearchResponse struct {
+	Result    BuildTargetSearchResult `json:"result"`
+	ErrorCode string                  `json:"error_code,omitempty"`
+	ErrorInfo string                  `json:"error_info,omitempty"`
+}
+
+type BuildLogSearchResult struct {
+	Data   []buildLogSearchData `json:"data"`
+	Cursor PhabricatorCursor    `json:"cursor"`
+}
+
+type BuildLogSearchResponse struct {
+	Result    BuildLogSearchResult `json:"result"`
+	ErrorCode string               `json:"error_code,omitempty"`
+	ErrorInfo string               `json:"error_info,omitempty"`
+}
+
+// SendMessage reports on a build target (through harbormaster.sendmessage):
+// that it is still working, or that it passed or failed, along with any unit
+// and lint results.
+func (f *HarbormasterServiceOp) SendMessage(ctx context.Context, message *BuildMessage) (*Response, error) {
+	if message == nil || message.Receiver == "" {
+		return nil, errors.New("a build message needs a receiver")
+	}
+
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterSendMessagePath, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.client.Do(req, nil)
+}
+
+// CreateArtifact attaches an artifact to a build target (through
+// harbormaster.createartifact). See URIArtifact and FileArtifact.
+func (f *HarbormasterServiceOp) CreateArtifact(ctx context.Context, createRequest *ArtifactCreateRequest) (*Artifact, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterCreateArtifactPath, createRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(ArtifactResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if len(root.Result.Data) < 1 {
+		return nil, resp, nil
+	}
+
+	artifact := root.Result.Data[0].toArtifact()
+	return &artifact, resp, err
+}
+
+// SearchBuilds searches for builds (through harbormaster.build.search).
+func (f *HarbormasterServiceOp) SearchBuilds(ctx context.Context, searchRequest *BuildSearchRequest) ([]Build, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterBuildSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BuildSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Build
+	for _, data := range root.Result.Data {
+		list = append(list, data.toBuild())
+	}
+	return list, resp, err
+}
+
+// SearchBuildables searches for buildables (through
+// harbormaster.buildable.search).
+func (f *HarbormasterServiceOp) SearchBuildables(ctx context.Context, searchRequest *BuildableSearchRequest) ([]Buildable, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterBuildableSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BuildableSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Buildable
+	for _, data := range root.Result.Data {
+		list = append(list, data.toBuildable())
+	}
+	return list, resp, err
+}
+
+// SearchTargets searches for build targets (through
+// harbormaster.target.search).
+func (f *HarbormasterServiceOp) SearchTargets(ctx context.Context, searchRequest *BuildTargetSearchRequest) ([]BuildTarget, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterTargetSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BuildTargetSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []BuildTarget
+	for _, data := range root.Result.Data {
+		list = append(list, data.toBuildTarget())
+	}
+	return list, resp, err
+}
+
+// SearchLogs searches for build logs (through harbormaster.log.search).
+func (f *HarbormasterServiceOp) SearchLogs(ctx context.Context, searchRequest *BuildLogSearchRequest) ([]BuildLog, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", harbormasterLogSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BuildLogSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []BuildLog
+	for _, data := range root.Result.Data {
+		list = append(list, data.toBuildLog())
+	}
+	return list, resp, err
+}
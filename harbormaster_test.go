@@ -0,0 +1,173 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHarbormaster_SendMessage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.sendmessage", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"receiver":"PHID-HMBT-1","type":"fail","unit":[{"name":"TestGolph","result":"fail","namespace":"golph","engine":"go test","duration":0.25,"details":"expected 1, got 2"}],"lint":[{"name":"gofmt","code":"GOFMT","severity":"error","path":"golph.go","line":12,"char":1,"description":"not formatted"}]}`)
+		fmt.Fprint(w, `{"result":null,"error_code":null,"error_info":null}`)
+	})
+
+	_, err := client.Harbormaster.SendMessage(ctx, &BuildMessage{
+		Receiver: "PHID-HMBT-1",
+		Type:     BuildMessageFail,
+		Unit:     []UnitResult{{Name: "TestGolph", Result: UnitResultFail, Namespace: "golph", Engine: "go test", Duration: 0.25, Details: "expected 1, got 2"}},
+		Lint:     []LintResult{{Name: "gofmt", Code: "GOFMT", Severity: LintSeverityError, Path: "golph.go", Line: 12, Char: 1, Description: "not formatted"}},
+	})
+	if err != nil {
+		t.Errorf("Harbormaster.SendMessage returned error: %v", err)
+	}
+
+	if _, err := client.Harbormaster.SendMessage(ctx, &BuildMessage{Type: BuildMessagePass}); err == nil {
+		t.Errorf("Harbormaster.SendMessage without a receiver returned no error")
+	}
+}
+
+func TestHarbormaster_CreateArtifact(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.createartifact", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"buildTargetPHID":"PHID-HMBT-1","artifactKey":"ci","artifactType":"uri","artifactData":{"name":"CI build","ui.external":true,"uri":"https://ci.example.com/42"}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":7,"phid":"PHID-HMBA-1","fields":{"buildTargetPHID":"PHID-HMBT-1","artifactType":"uri","artifactKey":"ci","isReleased":true,"dateCreated":1451337180,"dateModified":1451337180}}]},"error_code":null,"error_info":null}`)
+	})
+
+	artifact, _, err := client.Harbormaster.CreateArtifact(ctx, URIArtifact("PHID-HMBT-1", "ci", "https://ci.example.com/42", "CI build", true))
+	if err != nil {
+		t.Fatalf("Harbormaster.CreateArtifact returned error: %v", err)
+	}
+
+	expected := &Artifact{
+		ID:              7,
+		PHID:            "PHID-HMBA-1",
+		BuildTargetPHID: "PHID-HMBT-1",
+		ArtifactType:    "uri",
+		ArtifactKey:     "ci",
+		IsReleased:      true,
+		DateCreated:     &Timestamp{time.Unix(1451337180, 0)},
+		DateModified:    &Timestamp{time.Unix(1451337180, 0)},
+	}
+	if !reflect.DeepEqual(artifact, expected) {
+		t.Errorf("Harbormaster.CreateArtifact returned %+v, expected %+v", artifact, expected)
+	}
+}
+
+func TestHarbormaster_SearchBuilds(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.build.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"buildables":["PHID-HMBB-1"],"statuses":["failed"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":3,"type":"HMBD","phid":"PHID-HMBD-1","fields":{"buildablePHID":"PHID-HMBB-1","buildPlanPHID":"PHID-HMCP-1","buildStatus":{"value":"failed","name":"Failed","color.ansi":"red"},"initiatorPHID":"PHID-USER-1","name":"Run tests","dateCreated":1451337180,"dateModified":1451337280}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	builds, _, err := client.Harbormaster.SearchBuilds(ctx, &BuildSearchRequest{
+		Constraints: &BuildSearchConstraints{Buildables: []string{"PHID-HMBB-1"}, Statuses: []string{"failed"}},
+	})
+	if err != nil {
+		t.Fatalf("Harbormaster.SearchBuilds returned error: %v", err)
+	}
+
+	expected := []Build{{
+		ID:            3,
+		PHID:          "PHID-HMBD-1",
+		Name:          "Run tests",
+		BuildablePHID: "PHID-HMBB-1",
+		BuildPlanPHID: "PHID-HMCP-1",
+		InitiatorPHID: "PHID-USER-1",
+		Status:        "failed",
+		StatusName:    "Failed",
+		DateCreated:   &Timestamp{time.Unix(1451337180, 0)},
+		DateModified:  &Timestamp{time.Unix(1451337280, 0)},
+	}}
+	if !reflect.DeepEqual(builds, expected) {
+		t.Errorf("Harbormaster.SearchBuilds returned %+v, expected %+v", builds, expected)
+	}
+}
+
+func TestHarbormaster_SearchBuildables(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.buildable.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"objectPHIDs":["PHID-DIFF-1"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":1,"phid":"PHID-HMBB-1","fields":{"objectPHID":"PHID-DIFF-1","containerPHID":"PHID-DREV-1","buildableStatus":{"value":"passed"},"isManual":false,"uri":"https://phabricator.example.com/B1"}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	buildables, _, err := client.Harbormaster.SearchBuildables(ctx, &BuildableSearchRequest{
+		Constraints: &BuildableSearchConstraints{ObjectPHIDs: []string{"PHID-DIFF-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Harbormaster.SearchBuildables returned error: %v", err)
+	}
+
+	expected := []Buildable{{ID: 1, PHID: "PHID-HMBB-1", ObjectPHID: "PHID-DIFF-1", ContainerPHID: "PHID-DREV-1", Status: "passed", URI: "https://phabricator.example.com/B1"}}
+	if !reflect.DeepEqual(buildables, expected) {
+		t.Errorf("Harbormaster.SearchBuildables returned %+v, expected %+v", buildables, expected)
+	}
+}
+
+func TestHarbormaster_SearchTargets(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.target.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"buildPHIDs":["PHID-HMBD-1"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":9,"phid":"PHID-HMBT-1","fields":{"name":"Make HTTP Request","buildPHID":"PHID-HMBD-1","buildStepPHID":"PHID-HMCS-1","status":{"value":"target/waiting"},"buildGeneration":1,"epochStarted":1451337180,"epochCompleted":null}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	targets, _, err := client.Harbormaster.SearchTargets(ctx, &BuildTargetSearchRequest{
+		Constraints: &BuildTargetSearchConstraints{BuildPHIDs: []string{"PHID-HMBD-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Harbormaster.SearchTargets returned error: %v", err)
+	}
+
+	expected := []BuildTarget{{
+		ID:              9,
+		PHID:            "PHID-HMBT-1",
+		Name:            "Make HTTP Request",
+		BuildPHID:       "PHID-HMBD-1",
+		BuildStepPHID:   "PHID-HMCS-1",
+		Status:          "target/waiting",
+		BuildGeneration: 1,
+		DateStarted:     &Timestamp{time.Unix(1451337180, 0)},
+	}}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Harbormaster.SearchTargets returned %+v, expected %+v", targets, expected)
+	}
+}
+
+func TestHarbormaster_SearchLogs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/harbormaster.log.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"buildTargetPHIDs":["PHID-HMBT-1"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":2,"phid":"PHID-HMCL-1","fields":{"buildTargetPHID":"PHID-HMBT-1","byteLength":2048,"filePHID":"PHID-FILE-1"}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	logs, _, err := client.Harbormaster.SearchLogs(ctx, &BuildLogSearchRequest{
+		Constraints: &BuildLogSearchConstraints{BuildTargetPHIDs: []string{"PHID-HMBT-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Harbormaster.SearchLogs returned error: %v", err)
+	}
+
+	expected := []BuildLog{{ID: 2, PHID: "PHID-HMCL-1", BuildTargetPHID: "PHID-HMBT-1", ByteLength: 2048, FilePHID: "PHID-FILE-1"}}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Errorf("Harbormaster.SearchLogs returned %+v, expected %+v", logs, expected)
+	}
+}
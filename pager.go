@@ -0,0 +1,198 @@
+package golph
+
+import (
+	"context"
+	"strconv"
+)
+
+// defaultPageSize is the number of results requested per page when paging
+// through a result set, matching the Conduit default.
+const defaultPageSize = 100
+
+// PhabricatorCursor is the paging cursor returned by cursor based Conduit
+// methods such as *.search and project.query.
+type PhabricatorCursor struct {
+	Limit  int    `json:"limit"`
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+// PageFunc fetches a single page of results starting after the given cursor
+// (empty for the first page), using the context the pager was created with.
+// It returns the items on the page and the cursor of the following page,
+// which is empty once the results are exhausted.
+type PageFunc func(ctx context.Context, after string) ([]interface{}, string, *Response, error)
+
+// Pager walks through a paginated result set one item at a time, fetching
+// further pages as needed:
+//
+//	pager := client.Users.SearchPager(ctx, &golph.UserSearchRequest{})
+//	for pager.Next() {
+//		user := pager.Item().(golph.User)
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+type Pager struct {
+	// MaxResults caps the number of items the pager returns. Zero means no cap.
+	MaxResults int
+
+	ctx     context.Context
+	fetch   PageFunc
+	items   []interface{}
+	item    interface{}
+	after   string
+	started bool
+	count   int
+	resp    *Response
+	err     error
+}
+
+// NewPager returns a Pager that fetches its pages through fetch. Once ctx is
+// done the pager stops, and Err returns the context's error.
+func NewPager(ctx context.Context, fetch PageFunc) *Pager {
+	return &Pager{ctx: ctx, fetch: fetch}
+}
+
+// Next advances to the next item, fetching the next page when the current one
+// is used up. It returns false when the results are exhausted, MaxResults has
+// been reached, the context is done or an error occurred.
+func (p *Pager) Next() bool {
+	if p.err != nil {
+		return false
+	}
+
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	if p.MaxResults > 0 && p.count >= p.MaxResults {
+		return false
+	}
+
+	for len(p.items) == 0 {
+		if p.started && p.after == "" {
+			return false
+		}
+		p.started = true
+
+		items, after, resp, err := p.fetch(p.ctx, p.after)
+		p.resp = resp
+		if err != nil {
+			p.err = err
+			return false
+		}
+
+		// A cursor that doesn't move would page forever.
+		if after == p.after {
+			after = ""
+		}
+
+		p.items = items
+		p.after = after
+	}
+
+	p.item = p.items[0]
+	p.items = p.items[1:]
+	p.count++
+
+	return true
+}
+
+// Item returns the current item. Callers type assert it to the type of the
+// service that created the pager.
+func (p *Pager) Item() interface{} {
+	return p.item
+}
+
+// Err returns the error, if any, that stopped the pager.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// Response returns the response of the last page fetched.
+func (p *Pager) Response() *Response {
+	return p.resp
+}
+
+// Each calls fn with every remaining item, stopping early if fn returns an
+// error.
+func (p *Pager) Each(fn func(interface{}) error) error {
+	for p.Next() {
+		if err := fn(p.Item()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// listCap returns the most results a List call with opt returns: the smaller
+// of its Limit and MaxResults, or zero if neither is set.
+func listCap(opt *ListOptions) int {
+	if opt == nil {
+		return 0
+	}
+	if opt.Limit > 0 && (opt.MaxResults == 0 || opt.Limit < opt.MaxResults) {
+		return opt.Limit
+	}
+	return opt.MaxResults
+}
+
+// pageLimit returns the limit to request the next page with, once fetched
+// results have been read, so that no page runs past the cap of opt.
+func pageLimit(opt *ListOptions, fetched int) int {
+	limit := defaultPageSize
+	if opt != nil && opt.Limit > 0 {
+		limit = opt.Limit
+	}
+
+	if max := listCap(opt); max > 0 && max-fetched < limit {
+		limit = max - fetched
+	}
+	return limit
+}
+
+// offsetPageFunc adapts the offset/limit paging of the older *.query methods
+// to a PageFunc, carrying the next offset around as the cursor.
+func offsetPageFunc(opt *ListOptions, fetch func(context.Context, *ListOptions) ([]interface{}, *Response, error)) PageFunc {
+	page := ListOptions{}
+	if opt != nil {
+		page = *opt
+	}
+
+	start := page.Offset
+
+	return func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.Offset = start
+		if after != "" {
+			offset, err := strconv.Atoi(after)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			page.Offset = offset
+		}
+
+		fetched := page.Offset - start
+		page.Limit = pageLimit(opt, fetched)
+
+		items, resp, err := fetch(ctx, &page)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		next := ""
+		if fetched += len(items); len(items) >= page.Limit && (listCap(opt) == 0 || fetched < listCap(opt)) {
+			next = strconv.Itoa(page.Offset + len(items))
+		}
+
+		return items, next, resp, nil
+	}
+}
+
+// newPagerFor returns a pager capped at the Limit or MaxResults of opt.
+func newPagerFor(ctx context.Context, opt *ListOptions, fetch PageFunc) *Pager {
+	pager := NewPager(ctx, fetch)
+	pager.MaxResults = listCap(opt)
+	return pager
+}
@@ -0,0 +1,156 @@
+package golph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPager_FollowsCursor(t *testing.T) {
+	pages := map[string][]interface{}{
+		"":  {1, 2},
+		"2": {3, 4},
+		"4": {5},
+	}
+	next := map[string]string{"": "2", "2": "4", "4": ""}
+
+	var cursors []string
+	pager := NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		cursors = append(cursors, after)
+		return pages[after], next[after], nil, nil
+	})
+
+	var items []interface{}
+	for pager.Next() {
+		items = append(items, pager.Item())
+	}
+
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Pager.Err returned %v", err)
+	}
+
+	if expected := []interface{}{1, 2, 3, 4, 5}; !reflect.DeepEqual(items, expected) {
+		t.Errorf("Pager returned %v, expected %v", items, expected)
+	}
+
+	if expected := []string{"", "2", "4"}; !reflect.DeepEqual(cursors, expected) {
+		t.Errorf("Pager requested cursors %v, expected %v", cursors, expected)
+	}
+}
+
+func TestPager_MaxResults(t *testing.T) {
+	fetches := 0
+	pager := NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		fetches++
+		return []interface{}{1, 2}, fmt.Sprintf("%d", fetches), nil, nil
+	})
+	pager.MaxResults = 3
+
+	count := 0
+	err := pager.Each(func(item interface{}) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pager.Each returned %v", err)
+	}
+
+	if count != 3 || fetches != 2 {
+		t.Errorf("Pager returned %d items in %d fetches, expected 3 in 2", count, fetches)
+	}
+}
+
+func TestPager_Error(t *testing.T) {
+	pager := NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		if after == "" {
+			return []interface{}{1}, "1", nil, nil
+		}
+		return nil, "", nil, errors.New("boom")
+	})
+
+	count := 0
+	for pager.Next() {
+		count++
+	}
+
+	if count != 1 || pager.Err() == nil {
+		t.Errorf("Pager returned %d items and error %v, expected 1 item and an error", count, pager.Err())
+	}
+}
+
+func TestPager_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetches := 0
+	pager := NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		fetches++
+		return []interface{}{1}, fmt.Sprintf("%d", fetches), nil, nil
+	})
+
+	count := 0
+	for pager.Next() {
+		count++
+		cancel()
+	}
+
+	if count != 1 || fetches != 1 {
+		t.Errorf("Pager returned %d items in %d fetches after cancel, expected 1 in 1", count, fetches)
+	}
+	if pager.Err() != context.Canceled {
+		t.Errorf("Pager.Err returned %v, expected %v", pager.Err(), context.Canceled)
+	}
+}
+
+func TestPager_Offset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// 250 users, served limit at a time from offset
+	var requests []string
+	mux.HandleFunc("/api/user.query", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		requests = append(requests, fmt.Sprintf("%d+%d", offset, limit))
+
+		var users []string
+		for i := offset; i < offset+limit && i < 250; i++ {
+			users = append(users, fmt.Sprintf(`{"phid":"PHID-USER-%d"}`, i+1))
+		}
+		fmt.Fprintf(w, `{"result":[%s],"error_code":null,"error_info":null}`, strings.Join(users, ","))
+	})
+
+	cases := []struct {
+		opt      *ListOptions
+		count    int
+		requests []string
+	}{
+		{nil, 250, []string{"0+100", "100+100", "200+100"}},
+		{&ListOptions{Limit: 100}, 100, []string{"0+100"}},
+		{&ListOptions{Offset: 5, Limit: 10}, 10, []string{"5+10"}},
+		{&ListOptions{MaxResults: 150}, 150, []string{"0+100", "100+50"}},
+		{&ListOptions{Limit: 50, MaxResults: 120}, 50, []string{"0+50"}},
+	}
+
+	for _, c := range cases {
+		requests = nil
+
+		users, _, err := client.Users.List(ctx, c.opt)
+		if err != nil {
+			t.Fatalf("Users.List returned error: %v", err)
+		}
+
+		if len(users) != c.count {
+			t.Errorf("Users.List(%+v) returned %d users, expected %d", c.opt, len(users), c.count)
+		}
+		if !reflect.DeepEqual(requests, c.requests) {
+			t.Errorf("Users.List(%+v) requested %v, expected %v", c.opt, requests, c.requests)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package golph
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// PHID is the identifier Phabricator gives every object, like
+// PHID-TASK-cpbxbdjodclbv7bn6vcy. Its second segment names the type of object.
+type PHID string
+
+// Types of object a PHID can identify.
+const (
+	PHIDTypeTask        = "TASK"
+	PHIDTypeUser        = "USER"
+	PHIDTypeProject     = "PROJ"
+	PHIDTypeColumn      = "PCOL"
+	PHIDTypeRevision    = "DREV"
+	PHIDTypeDiff        = "DIFF"
+	PHIDTypeCommit      = "CMIT"
+	PHIDTypeRepository  = "REPO"
+	PHIDTypePaste       = "PSTE"
+	PHIDTypeFile        = "FILE"
+	PHIDTypeTransaction = "XACT"
+)
+
+// phidPattern matches a PHID, including transaction PHIDs, which name the
+// type of the object they belong to as well, like PHID-XACT-TASK-5wsmvd3ftj.
+var phidPattern = regexp.MustCompile(`^PHID-([A-Z0-9]{4})(-[A-Z0-9]{4})?-[a-z0-9]+$`)
+
+// ParsePHID returns s as a PHID, or an error if it isn't one.
+func ParsePHID(s string) (PHID, error) {
+	p := PHID(s)
+	if !p.Valid() {
+		return "", fmt.Errorf("%q is not a PHID", s)
+	}
+	return p, nil
+}
+
+// Valid reports whether p is well formed.
+func (p PHID) Valid() bool {
+	return phidPattern.MatchString(string(p))
+}
+
+// Type returns the type of object p identifies, like PHIDTypeTask, or "" if p
+// isn't a PHID.
+func (p PHID) Type() string {
+	match := phidPattern.FindStringSubmatch(string(p))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func (p PHID) String() string {
+	return string(p)
+}
+
+// MonogramKind is the prefix of a monogram, which says what it refers to.
+type MonogramKind string
+
+// Kinds of monogram ParseMonogram understands.
+const (
+	MonogramTask     MonogramKind = "T"
+	MonogramRevision MonogramKind = "D"
+	MonogramPaste    MonogramKind = "P"
+	MonogramFile     MonogramKind = "F"
+	MonogramCommit   MonogramKind = "r"
+	MonogramUser     MonogramKind = "@"
+	MonogramProject  MonogramKind = "#"
+)
+
+// monogramPHIDTypes are the types of object each kind of monogram refers to.
+var monogramPHIDTypes = map[MonogramKind]string{
+	MonogramTask:     PHIDTypeTask,
+	MonogramRevision: PHIDTypeRevision,
+	MonogramPaste:    PHIDTypePaste,
+	MonogramFile:     PHIDTypeFile,
+	MonogramCommit:   PHIDTypeCommit,
+	MonogramUser:     PHIDTypeUser,
+	MonogramProject:  PHIDTypeProject,
+}
+
+// Monogram is the short name people use for an object, like T123 for a task,
+// rGOLPHabc123 for a commit or @alice for a user.
+type Monogram struct {
+	Kind MonogramKind
+
+	// ID of a task, revision, paste or file
+	ID int
+
+	// Name of a user or slug of a project
+	Name string
+
+	// Callsign of the repository and hash of a commit
+	Repository string
+	Commit     string
+}
+
+var (
+	idMonogramPattern      = regexp.MustCompile(`^([TDPF])([1-9][0-9]*)$`)
+	commitMonogramPattern  = regexp.MustCompile(`^r([A-Z]+)([0-9a-f]{5,40})$`)
+	userMonogramPattern    = regexp.MustCompile(`^@([A-Za-z0-9._-]+)$`)
+	projectMonogramPattern = regexp.MustCompile(`^#([^\s#]+)$`)
+)
+
+// ParseMonogram parses "T123", "D45", "P12", "F99", "rGOLPHabc123", "@alice"
+// or "#golph".
+func ParseMonogram(s string) (Monogram, error) {
+	if match := idMonogramPattern.FindStringSubmatch(s); match != nil {
+		id, err := strconv.Atoi(match[2])
+		if err != nil {
+			return Monogram{}, fmt.Errorf("%q is not a monogram: %v", s, err)
+		}
+		return Monogram{Kind: MonogramKind(match[1]), ID: id}, nil
+	}
+
+	if match := commitMonogramPattern.FindStringSubmatch(s); match != nil {
+		return Monogram{Kind: MonogramCommit, Repository: match[1], Commit: match[2]}, nil
+	}
+
+	if match := userMonogramPattern.FindStringSubmatch(s); match != nil {
+		return Monogram{Kind: MonogramUser, Name: match[1]}, nil
+	}
+
+	if match := projectMonogramPattern.FindStringSubmatch(s); match != nil {
+		return Monogram{Kind: MonogramProject, Name: match[1]}, nil
+	}
+
+	return Monogram{}, fmt.Errorf("%q is not a monogram", s)
+}
+
+func (m Monogram) String() string {
+	switch m.Kind {
+	case MonogramCommit:
+		return "r" + m.Repository + m.Commit
+	case MonogramUser, MonogramProject:
+		return string(m.Kind) + m.Name
+	default:
+		return string(m.Kind) + strconv.Itoa(m.ID)
+	}
+}
+
+// PHIDType returns the type of object the monogram refers to.
+func (m Monogram) PHIDType() string {
+	return monogramPHIDTypes[m.Kind]
+}
+
+// LookupMonogram finds the object a monogram refers to, through the service
+// for its kind: a *Task, *Revision, *Commit, *User or *Project, or for pastes
+// and files a *PHIDInfo from phid.lookup. It returns nil if there is no such
+// object.
+func (f *PHIDsServiceOp) LookupMonogram(ctx context.Context, m Monogram) (interface{}, *Response, error) {
+	switch m.Kind {
+	case MonogramTask:
+		tasks, resp, err := f.client.Tasks.Find(ctx, &TaskFindRequest{
+			Constraints: &TaskFindConstraints{IDs: []int{m.ID}},
+		})
+		if err != nil || len(tasks) < 1 {
+			return nil, resp, err
+		}
+		return &tasks[0], resp, err
+
+	case MonogramRevision:
+		revisions, resp, err := f.client.Differential.SearchRevisions(ctx, &RevisionSearchRequest{
+			Constraints: &RevisionSearchConstraints{IDs: []int{m.ID}},
+		})
+		if err != nil || len(revisions) < 1 {
+			return nil, resp, err
+		}
+		return &revisions[0], resp, err
+
+	case MonogramCommit:
+		commits, resp, err := f.client.Repositories.SearchCommits(ctx, &CommitSearchRequest{
+			Constraints: &CommitSearchConstraints{Identifiers: []string{m.String()}},
+		})
+		if err != nil || len(commits) < 1 {
+			return nil, resp, err
+		}
+		return &commits[0], resp, err
+
+	case MonogramUser:
+		user, resp, err := f.client.Users.Get(ctx, m.Name)
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		if err != nil {
+			return nil, resp, err
+		}
+		return user, resp, err
+
+	case MonogramProject:
+		projects, resp, err := f.client.Projects.Find(ctx, &ProjectFindRequest{
+			Constraints: &ProjectFindConstraints{Slugs: []string{m.Name}},
+			Attachments: &ProjectFindAttachments{Members: true},
+		})
+		if err != nil || len(projects) < 1 {
+			return nil, resp, err
+		}
+		return &projects[0], resp, err
+
+	case MonogramPaste, MonogramFile:
+		name := m.String()
+		infos, resp, err := f.Lookup(ctx, name)
+		if err != nil {
+			return nil, resp, err
+		}
+		info, ok := infos[name]
+		if !ok {
+			return nil, resp, nil
+		}
+		return &info, resp, nil
+	}
+
+	return nil, nil, fmt.Errorf("monogram %q can't be looked up", m)
+}
@@ -0,0 +1,124 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPHID_Type(t *testing.T) {
+	cases := map[PHID]string{
+		"PHID-TASK-cpbxbdjodclbv7bn6vcy":      PHIDTypeTask,
+		"PHID-USER-1":                         PHIDTypeUser,
+		"PHID-XACT-TASK-5wsmvd3ftjyyyc2":      PHIDTypeTransaction,
+		"PHID-PROJ-":                          "",
+		"PHID-task-cpbxbdjodclbv7bn6vcy":      "",
+		"T123":                                "",
+		"PHID-TASK-cpbxbdjodclbv7bn6vcy trap": "",
+	}
+
+	for phid, expected := range cases {
+		if got := phid.Type(); got != expected {
+			t.Errorf("PHID(%q).Type() = %q, expected %q", phid, got, expected)
+		}
+		if phid.Valid() != (expected != "") {
+			t.Errorf("PHID(%q).Valid() = %v", phid, phid.Valid())
+		}
+	}
+
+	if _, err := ParsePHID("T123"); err == nil {
+		t.Errorf("ParsePHID(%q) returned no error", "T123")
+	}
+}
+
+func TestParseMonogram(t *testing.T) {
+	cases := map[string]Monogram{
+		"T123":                   {Kind: MonogramTask, ID: 123},
+		"D45":                    {Kind: MonogramRevision, ID: 45},
+		"P12":                    {Kind: MonogramPaste, ID: 12},
+		"F99":                    {Kind: MonogramFile, ID: 99},
+		"rPHABabc123":            {Kind: MonogramCommit, Repository: "PHAB", Commit: "abc123"},
+		"rPHAB1234abc":           {Kind: MonogramCommit, Repository: "PHAB", Commit: "1234abc"},
+		"rGOLPH0123456789abcdef": {Kind: MonogramCommit, Repository: "GOLPH", Commit: "0123456789abcdef"},
+		"@alice.example":         {Kind: MonogramUser, Name: "alice.example"},
+		"#golph-sprint_12":       {Kind: MonogramProject, Name: "golph-sprint_12"},
+	}
+
+	for s, expected := range cases {
+		m, err := ParseMonogram(s)
+		if err != nil {
+			t.Errorf("ParseMonogram(%q) returned error: %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("ParseMonogram(%q) = %+v, expected %+v", s, m, expected)
+		}
+		if m.String() != s {
+			t.Errorf("ParseMonogram(%q).String() = %q", s, m.String())
+		}
+	}
+
+	for _, s := range []string{"", "T", "T0", "X12", "rphababc123", "@", "#", "T12 ", "D4a"} {
+		if m, err := ParseMonogram(s); err == nil {
+			t.Errorf("ParseMonogram(%q) = %+v, expected an error", s, m)
+		}
+	}
+
+	if m, _ := ParseMonogram("D45"); m.PHIDType() != PHIDTypeRevision {
+		t.Errorf("D45 refers to %q, expected %q", m.PHIDType(), PHIDTypeRevision)
+	}
+}
+
+func TestPHIDs_LookupMonogramTask(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"ids":[123]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":123,"type":"TASK","phid":"PHID-TASK-1","fields":{"name":"Write a client"}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	found, _, err := client.PHIDs.LookupMonogram(ctx, Monogram{Kind: MonogramTask, ID: 123})
+	if err != nil {
+		t.Fatalf("PHIDs.LookupMonogram returned error: %v", err)
+	}
+
+	task, ok := found.(*Task)
+	if !ok || task.PHID != "PHID-TASK-1" || task.Title != "Write a client" {
+		t.Errorf("PHIDs.LookupMonogram returned %+v, expected task PHID-TASK-1", found)
+	}
+}
+
+func TestPHIDs_LookupMonogramPaste(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/phid.lookup", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"names":["P12"]}`)
+		fmt.Fprint(w, `{"result":{"P12":{"phid":"PHID-PSTE-1","type":"PSTE","name":"P12"}},"error_code":null,"error_info":null}`)
+	})
+
+	found, _, err := client.PHIDs.LookupMonogram(ctx, Monogram{Kind: MonogramPaste, ID: 12})
+	if err != nil {
+		t.Fatalf("PHIDs.LookupMonogram returned error: %v", err)
+	}
+
+	if info, ok := found.(*PHIDInfo); !ok || info.PHID != "PHID-PSTE-1" {
+		t.Errorf("PHIDs.LookupMonogram returned %+v, expected PHID-PSTE-1", found)
+	}
+}
+
+func TestPHIDs_LookupMonogramMissingUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	found, _, err := client.PHIDs.LookupMonogram(ctx, Monogram{Kind: MonogramUser, Name: "nobody"})
+	if err != nil || found != nil {
+		t.Errorf("PHIDs.LookupMonogram returned %+v, %v, expected nil", found, err)
+	}
+}
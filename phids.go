@@ -0,0 +1,115 @@
+package golph
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const phidsQueryPath = "api/phid.query"
+const phidsLookupPath = "api/phid.lookup"
+
+// PHIDsService is an interface for looking up any kind of object by PHID or by
+// name, such as T123 or @alice.
+// See: https://secure.phabricator.com/conduit/method/phid.query/
+type PHIDsService interface {
+	Query(context.Context, ...string) (PHIDInfoMap, *Response, error)
+	Lookup(context.Context, ...string) (PHIDInfoMap, *Response, error)
+	LookupMonogram(context.Context, Monogram) (interface{}, *Response, error)
+}
+
+// PHIDsServiceOp handles communication with the phid conduit methods
+type PHIDsServiceOp struct {
+	client *Client
+}
+
+var _ PHIDsService = &PHIDsServiceOp{}
+
+// PHIDInfo describes the object behind a PHID.
+/*
+{
+  "phid": "PHID-TASK-1",
+  "uri": "https://phabricator.example.com/T1",
+  "typeName": "Maniphest Task",
+  "type": "TASK",
+  "name": "T1",
+  "fullName": "T1: Write a client",
+  "status": "open"
+}
+*/
+type PHIDInfo struct {
+	PHID     string `json:"phid"`
+	URI      string `json:"uri"`
+	TypeName string `json:"typeName"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	FullName string `json:"fullName"`
+	Status   string `json:"status"`
+}
+
+func (p PHIDInfo) String() string {
+	return Stringify(p)
+}
+
+// IsClosed reports whether the object is closed, like a resolved task or an
+// archived project.
+func (p PHIDInfo) IsClosed() bool {
+	return p.Status == "closed"
+}
+
+// PHIDInfoMap holds looked up objects, keyed by PHID for phid.query and by
+// name for phid.lookup.
+type PHIDInfoMap map[string]PHIDInfo
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *PHIDInfoMap) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = PHIDInfoMap{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]PHIDInfo)(m))
+}
+
+// PHIDQueryRequest represents a request to phid.query.
+type PHIDQueryRequest struct {
+	PHIDs []string `json:"phids"`
+}
+
+// PHIDLookupRequest represents a request to phid.lookup.
+type PHIDLookupRequest struct {
+	Names []string `json:"names"`
+}
+
+type PHIDInfoResponse struct {
+	Result    PHIDInfoMap `json:"result"`
+	ErrorCode string      `json:"error_code,omitempty"`
+	ErrorInfo string      `json:"error_info,omitempty"`
+}
+
+// Query looks up objects by PHID (through phid.query). PHIDs that don't exist
+// or can't be seen are left out of the result.
+func (f *PHIDsServiceOp) Query(ctx context.Context, phids ...string) (PHIDInfoMap, *Response, error) {
+	return f.query(ctx, phidsQueryPath, &PHIDQueryRequest{PHIDs: phids})
+}
+
+// Lookup looks up objects by name (through phid.lookup), such as "T123",
+// "D45", "@alice" or "#golph". Names that don't match anything are left out of
+// the result.
+func (f *PHIDsServiceOp) Lookup(ctx context.Context, names ...string) (PHIDInfoMap, *Response, error) {
+	return f.query(ctx, phidsLookupPath, &PHIDLookupRequest{Names: names})
+}
+
+func (f *PHIDsServiceOp) query(ctx context.Context, path string, body interface{}) (PHIDInfoMap, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(PHIDInfoResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return root.Result, resp, err
+}
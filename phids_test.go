@@ -0,0 +1,63 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPHIDs_Query(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/phid.query", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"phids":["PHID-TASK-1","PHID-USER-404"]}`)
+		fmt.Fprint(w, `{"result":{"PHID-TASK-1":{"phid":"PHID-TASK-1","uri":"https://phabricator.example.com/T1","typeName":"Maniphest Task","type":"TASK","name":"T1","fullName":"T1: Write a client","status":"closed"}},"error_code":null,"error_info":null}`)
+	})
+
+	infos, _, err := client.PHIDs.Query(ctx, "PHID-TASK-1", "PHID-USER-404")
+	if err != nil {
+		t.Fatalf("PHIDs.Query returned error: %v", err)
+	}
+
+	expected := PHIDInfoMap{
+		"PHID-TASK-1": {
+			PHID:     "PHID-TASK-1",
+			URI:      "https://phabricator.example.com/T1",
+			TypeName: "Maniphest Task",
+			Type:     "TASK",
+			Name:     "T1",
+			FullName: "T1: Write a client",
+			Status:   "closed",
+		},
+	}
+	if !reflect.DeepEqual(infos, expected) {
+		t.Errorf("PHIDs.Query returned %+v, expected %+v", infos, expected)
+	}
+
+	if !infos["PHID-TASK-1"].IsClosed() {
+		t.Errorf("PHIDInfo.IsClosed() = false, expected true")
+	}
+}
+
+func TestPHIDs_LookupNothing(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/phid.lookup", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"names":["T404"]}`)
+		fmt.Fprint(w, `{"result":[],"error_code":null,"error_info":null}`)
+	})
+
+	infos, _, err := client.PHIDs.Lookup(ctx, "T404")
+	if err != nil {
+		t.Fatalf("PHIDs.Lookup returned error: %v", err)
+	}
+
+	if len(infos) != 0 {
+		t.Errorf("PHIDs.Lookup returned %+v, expected nothing", infos)
+	}
+}
@@ -1,22 +1,38 @@
 package golph
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"sort"
 	//"net/url"
 )
 
 const projectsQueryPath = "api/project.query"
 const projectsCreatePath = "api/project.create"
+const projectsSearchPath = "api/project.search"
+const projectsEditMethod = "project.edit"
 
 // ProjectsService is an interface for interfacing with Projects
 // See: https://secure.phabricator.com/conduit/ (and search for projects)
 type ProjectsService interface {
-	List(*ListOptions) ([]Project, *Response, error)
-	Get(string) (*Project, *Response, error)
-	Create(*ProjectCreateRequest) (*Project, *Response, error)
-	Update(*ProjectUpdateRequest) (*Response, error)
-	Delete(*Project) (*Response, error)
+	List(context.Context, *ListOptions) ([]Project, *Response, error)
+	ListPager(context.Context, *ListOptions) *Pager
+	Get(context.Context, string) (*Project, *Response, error)
+	Create(context.Context, *ProjectCreateRequest) (*Project, *Response, error)
+	Update(context.Context, *ProjectUpdateRequest) (*Project, *Response, error)
+	Delete(context.Context, *Project) (*Response, error)
+	AddMembers(context.Context, string, ...string) (*Project, *Response, error)
+	RemoveMembers(context.Context, string, ...string) (*Project, *Response, error)
+	Join(context.Context, string) (*Project, *Response, error)
+	Leave(context.Context, string) (*Project, *Response, error)
+	SearchColumns(context.Context, *ColumnSearchRequest) ([]Column, *Response, error)
+	Board(context.Context, string) (*Board, *Response, error)
+	Find(context.Context, *ProjectFindRequest) ([]Project, *Response, error)
+	FindPager(context.Context, *ProjectFindRequest) *Pager
+	CreateMilestone(context.Context, string, string) (*Project, *Response, error)
+	CreateSubproject(context.Context, string, string) (*Project, *Response, error)
+	Tree(context.Context, string) (*ProjectTree, *Response, error)
 }
 
 // ProjectsServiceOp handles communication with the conduit methods
@@ -28,20 +44,41 @@ var _ ProjectsService = &ProjectsServiceOp{}
 
 // Project represents a Phabricator project.
 type Project struct {
-	PHID    string   `json:"phid"`
+	PHID    PHID     `json:"phid"`
 	Name    string   `json:"name"`
 	Tags    []string `json:"slugs"`
 	Members []string `json:"members"`
 	Icon    string   `json:"icon"`
 	Color   string   `json:"color"`
+
+	// Only filled in for projects read through project.search, as Find and
+	// the methods that change a project do.
+	ID          int    `json:"-"`
+	Description string `json:"description,omitempty"`
+	// Parent is the project a subproject or milestone belongs to.
+	Parent string `json:"parentPHID,omitempty"`
+	// Milestone is the number of a milestone within its parent, zero for
+	// projects that aren't milestones.
+	Milestone int `json:"milestone,omitempty"`
+	// Depth is how many ancestors the project has.
+	Depth int `json:"depth,omitempty"`
+	// Ancestors lists the PHIDs of the projects above this one, when Find was
+	// asked to attach them.
+	Ancestors []string `json:"ancestorPHIDs,omitempty"`
+}
+
+// IsMilestone reports whether the project is a milestone of its parent.
+func (f Project) IsMilestone() bool {
+	return f.Milestone > 0
 }
 
 func (f Project) String() string {
 	return Stringify(f)
 }
 
+// ProjectSearchRequest looks projects up by name through project.query.
 type ProjectSearchRequest struct {
-	Names string `form:"names"`
+	Names []string `json:"names"`
 }
 
 // ProjectCreateRequest represents a request to create a Project.
@@ -53,25 +90,251 @@ type ProjectCreateRequest struct {
 	Color   string `form:"color"`
 }
 
-// ProjectUpdateRequest represents a request to update a Project.
-// Note: Phabricator doesn't support this, I'm just optimistic.
+// ProjectUpdateRequest represents a request to update a Project through
+// project.edit. Empty fields are left as they are. A project can't be moved
+// under another once created; use CreateSubproject or CreateMilestone.
 type ProjectUpdateRequest struct {
-	PHID    string   `json:"phid"`
-	Name    string   `json:"name"`
-	Tags    []string `json:"tags,omitempty"`
+	PHID string `json:"phid"`
+	Name string `json:"name"`
+	// Tags replaces the project's hashtags.
+	Tags []string `json:"tags,omitempty"`
+	// Members replaces the project's members.
 	Members []string `json:"members,omitempty"`
-	Icon    string   `json:"icon,omitempty"`
-	Color   string   `json:"color,omitempty"`
+	// Icon is an icon key such as "project" or "umbrella".
+	Icon        string `json:"icon,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// transactions returns the project.edit transactions that make the update.
+func (r *ProjectUpdateRequest) transactions() []Transaction {
+	var txns []Transaction
+	if r.Name != "" {
+		txns = append(txns, SetProjectName(r.Name))
+	}
+	if r.Tags != nil {
+		txns = append(txns, Transaction{Type: "slugs", Value: r.Tags})
+	}
+	if r.Members != nil {
+		txns = append(txns, Transaction{Type: "members.set", Value: r.Members})
+	}
+	if r.Icon != "" {
+		txns = append(txns, Transaction{Type: "icon", Value: r.Icon})
+	}
+	if r.Color != "" {
+		txns = append(txns, Transaction{Type: "color", Value: r.Color})
+	}
+	if r.Description != "" {
+		txns = append(txns, SetDescription(r.Description))
+	}
+	return txns
+}
+
+// SetProjectName renames a project.
+func SetProjectName(name string) Transaction {
+	return Transaction{Type: "name", Value: name}
+}
+
+// AddMembers adds users to a project.
+func AddMembers(userPHIDs ...string) Transaction {
+	return Transaction{Type: "members.add", Value: userPHIDs}
+}
+
+// RemoveMembers removes users from a project.
+func RemoveMembers(userPHIDs ...string) Transaction {
+	return Transaction{Type: "members.remove", Value: userPHIDs}
+}
+
+// ArchiveProject archives a project, hiding it from most of Phabricator.
+func ArchiveProject() Transaction {
+	return SetStatus("archived")
+}
+
+// CreateMilestone creates the next milestone of a project, such as a sprint.
+func CreateMilestone(parentPHID string) Transaction {
+	return Transaction{Type: "milestone", Value: parentPHID}
+}
+
+// CreateSubproject creates a project as a subproject of another.
+func CreateSubproject(parentPHID string) Transaction {
+	return Transaction{Type: "parent", Value: parentPHID}
+}
+
+// ProjectFindConstraints narrows down the results of project.search.
+type ProjectFindConstraints struct {
+	IDs         []int    `json:"ids,omitempty"`
+	PHIDs       []string `json:"phids,omitempty"`
+	Slugs       []string `json:"slugs,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Members     []string `json:"members,omitempty"`
+	Parents     []string `json:"parents,omitempty"`
+	Ancestors   []string `json:"ancestors,omitempty"`
+	IsMilestone *bool    `json:"isMilestone,omitempty"`
+	IsRoot      *bool    `json:"isRoot,omitempty"`
+	MinDepth    *int     `json:"minDepth,omitempty"`
+	MaxDepth    *int     `json:"maxDepth,omitempty"`
+	// Status is "active", "archived" or "all".
+	Status string `json:"status,omitempty"`
+	Query  string `json:"query,omitempty"`
+}
+
+// ProjectFindAttachments asks project.search for extra data.
+type ProjectFindAttachments struct {
+	Members   bool `json:"members,omitempty"`
+	Ancestors bool `json:"ancestors,omitempty"`
+}
+
+// ProjectFindRequest represents a request to project.search.
+type ProjectFindRequest struct {
+	QueryKey    string                  `json:"queryKey,omitempty"`
+	Constraints *ProjectFindConstraints `json:"constraints,omitempty"`
+	Attachments *ProjectFindAttachments `json:"attachments,omitempty"`
+	Order       string                  `json:"order,omitempty"`
+	Before      string                  `json:"before,omitempty"`
+	After       string                  `json:"after,omitempty"`
+	Limit       int                     `json:"limit,omitempty"`
+}
+
+// ProjectTree is a project along with its subprojects and milestones, and
+// theirs in turn.
+type ProjectTree struct {
+	Project  Project
+	Children []*ProjectTree
+}
+
+// Walk calls fn with every project in the tree, parents before their
+// children, along with its depth below the root of the tree. It stops early if
+// fn returns an error.
+func (t *ProjectTree) Walk(fn func(project Project, depth int) error) error {
+	return t.walk(fn, 0)
+}
+
+func (t *ProjectTree) walk(fn func(Project, int) error, depth int) error {
+	if err := fn(t.Project, depth); err != nil {
+		return err
+	}
+	for _, child := range t.Children {
+		if err := child.walk(fn, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Milestones returns the milestones directly under the project, in order.
+func (t *ProjectTree) Milestones() []Project {
+	var milestones []Project
+	for _, child := range t.Children {
+		if child.Project.IsMilestone() {
+			milestones = append(milestones, child.Project)
+		}
+	}
+	return milestones
+}
+
+// projectRef is how project.search refers to other projects.
+type projectRef struct {
+	ID   int    `json:"id"`
+	PHID string `json:"phid"`
+	Name string `json:"name"`
+}
+
+type projectSearchFields struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Icon        struct {
+		Key string `json:"key"`
+	} `json:"icon"`
+	Color struct {
+		Key string `json:"key"`
+	} `json:"color"`
+	Milestone int         `json:"milestone"`
+	Depth     int         `json:"depth"`
+	Parent    *projectRef `json:"parent"`
+}
+
+type projectSearchAttachments struct {
+	Members struct {
+		Members []struct {
+			PHID string `json:"phid"`
+		} `json:"members"`
+	} `json:"members"`
+	Ancestors struct {
+		Ancestors []projectRef `json:"ancestors"`
+	} `json:"ancestors"`
+}
+
+type projectSearchData struct {
+	ID          int                      `json:"id"`
+	PHID        PHID                     `json:"phid"`
+	Fields      projectSearchFields      `json:"fields"`
+	Attachments projectSearchAttachments `json:"attachments"`
+}
+
+func (d projectSearchData) toProject() Project {
+	project := Project{
+		ID:          d.ID,
+		PHID:        d.PHID,
+		Name:        d.Fields.Name,
+		Icon:        d.Fields.Icon.Key,
+		Color:       d.Fields.Color.Key,
+		Description: d.Fields.Description,
+		Milestone:   d.Fields.Milestone,
+		Depth:       d.Fields.Depth,
+	}
+
+	if d.Fields.Parent != nil {
+		project.Parent = d.Fields.Parent.PHID
+	}
+
+	for _, ancestor := range d.Attachments.Ancestors.Ancestors {
+		project.Ancestors = append(project.Ancestors, ancestor.PHID)
+	}
+
+	if d.Fields.Slug != "" {
+		project.Tags = []string{d.Fields.Slug}
+	}
+
+	for _, member := range d.Attachments.Members.Members {
+		project.Members = append(project.Members, member.PHID)
+	}
+
+	return project
+}
+
+type ProjectSearchResult struct {
+	Data   []projectSearchData `json:"data"`
+	Cursor PhabricatorCursor   `json:"cursor"`
+}
+
+type ProjectSearchResponse struct {
+	Result    ProjectSearchResult `json:"result"`
+	ErrorCode string              `json:"error_code,omitempty"`
+	ErrorInfo string              `json:"error_info,omitempty"`
+}
+
+// ProjectMap is a set of projects keyed by PHID.
+type ProjectMap map[string]Project
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *ProjectMap) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = ProjectMap{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]Project)(m))
 }
 
 type ProjectResult struct {
-	Data map[string]Project `json:"data"`
+	Data   ProjectMap        `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
 }
 
-type PhabricatorCursor struct {
-	Limit  int    `json:"limit"`
-	After  string `json:"after"`
-	Before string `json:"before"`
+// ProjectQueryRequest carries the cursor for paging through project.query.
+type ProjectQueryRequest struct {
+	After string `form:"after,omitempty"`
 }
 
 type ProjectCreateResponse struct {
@@ -81,46 +344,81 @@ type ProjectCreateResponse struct {
 }
 
 type ProjectResponse struct {
-	Result    ProjectResult     `json:"result"`
-	Cursor    PhabricatorCursor `json:"cursor"`
-	ErrorCode string            `json:"error_code,omitempty"`
-	ErrorInfo string            `json:"error_info,omitempty"`
+	Result    ProjectResult `json:"result"`
+	ErrorCode string        `json:"error_code,omitempty"`
+	ErrorInfo string        `json:"error_info,omitempty"`
 }
 
-// List all projects.
-func (f *ProjectsServiceOp) List(opt *ListOptions) ([]Project, *Response, error) {
-	path := projectsQueryPath
-	path, err := addOptions(path, opt)
-	if err != nil {
-		return nil, nil, err
-	}
+// List projects, following the project.query cursor until the Limit or
+// MaxResults of opt is reached, or every project has been read.
+func (f *ProjectsServiceOp) List(ctx context.Context, opt *ListOptions) ([]Project, *Response, error) {
+	pager := f.ListPager(ctx, opt)
 
-	req, err := f.client.NewRequest("POST", path, nil)
-	if err != nil {
-		return nil, nil, err
+	var list []Project
+	for pager.Next() {
+		list = append(list, pager.Item().(Project))
 	}
 
-	root := new(ProjectResponse)
-	resp, err := f.client.Do(req, root)
-	if err != nil {
-		return nil, resp, err
+	return list, pager.Response(), pager.Err()
+}
+
+// ListPager returns a Pager over all projects. Items are Project values.
+func (f *ProjectsServiceOp) ListPager(ctx context.Context, opt *ListOptions) *Pager {
+	page := ListOptions{}
+	if opt != nil {
+		page = *opt
 	}
 
-	var list []Project
-	for _, project := range root.Result.Data {
-		list = append(list, project)
+	fetched := 0
+	return newPagerFor(ctx, opt, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.Limit = pageLimit(opt, fetched)
+
+		path := projectsQueryPath
+		path, err := addOptions(path, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		req, err := f.client.NewRequest(ctx, "POST", path, &ProjectQueryRequest{After: after})
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(ProjectResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		items := projectItems(root.Result.Data)
+		fetched += len(items)
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// projectItems returns the projects of a project.query result ordered by PHID,
+// since Conduit hands them back as a map.
+func projectItems(data ProjectMap) []interface{} {
+	var phids []string
+	for phid := range data {
+		phids = append(phids, phid)
 	}
+	sort.Strings(phids)
 
-	return list, resp, err
+	var items []interface{}
+	for _, phid := range phids {
+		items = append(items, data[phid])
+	}
+	return items
 }
 
 // Get an individual project.
-func (f *ProjectsServiceOp) Get(name string) (*Project, *Response, error) {
+func (f *ProjectsServiceOp) Get(ctx context.Context, name string) (*Project, *Response, error) {
 	searchRequest := &ProjectSearchRequest{
-		Names: fmt.Sprintf("[\"%s\"]", name), // This is a JSON encoded string which is silly
+		Names: []string{name},
 	}
 
-	req, err := f.client.NewRequest("POST", projectsQueryPath, searchRequest)
+	req, err := f.client.NewJSONRequest(ctx, "POST", projectsQueryPath, searchRequest)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -144,10 +442,10 @@ func (f *ProjectsServiceOp) Get(name string) (*Project, *Response, error) {
 }
 
 // Create a project
-func (f *ProjectsServiceOp) Create(createRequest *ProjectCreateRequest) (*Project, *Response, error) {
+func (f *ProjectsServiceOp) Create(ctx context.Context, createRequest *ProjectCreateRequest) (*Project, *Response, error) {
 	path := projectsCreatePath
 
-	req, err := f.client.NewRequest("POST", path, createRequest)
+	req, err := f.client.NewRequest(ctx, "POST", path, createRequest)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -161,12 +459,193 @@ func (f *ProjectsServiceOp) Create(createRequest *ProjectCreateRequest) (*Projec
 	return &root.Result, resp, err
 }
 
-// Update a Project (Not Available Yet!)
-func (f *ProjectsServiceOp) Update(project *ProjectUpdateRequest) (*Response, error) {
-	return nil, errors.New("Update is not available for Projects")
+// Update a Project (through project.edit), returning it as it is afterwards.
+func (f *ProjectsServiceOp) Update(ctx context.Context, project *ProjectUpdateRequest) (*Project, *Response, error) {
+	if project == nil || project.PHID == "" {
+		return nil, nil, errors.New("Update needs the PHID of the project")
+	}
+
+	return f.edit(ctx, project.PHID, project.transactions()...)
+}
+
+// Delete a Project. Phabricator never deletes projects, so this archives it.
+func (f *ProjectsServiceOp) Delete(ctx context.Context, project *Project) (*Response, error) {
+	if project == nil || project.PHID == "" {
+		return nil, errors.New("Delete needs the PHID of the project")
+	}
+
+	_, resp, err := f.client.Edit(ctx, projectsEditMethod, project.PHID.String(), []Transaction{ArchiveProject()})
+	return resp, err
+}
+
+// AddMembers adds users to a project, returning it as it is afterwards.
+func (f *ProjectsServiceOp) AddMembers(ctx context.Context, projectPHID string, userPHIDs ...string) (*Project, *Response, error) {
+	return f.edit(ctx, projectPHID, AddMembers(userPHIDs...))
+}
+
+// RemoveMembers removes users from a project, returning it as it is afterwards.
+func (f *ProjectsServiceOp) RemoveMembers(ctx context.Context, projectPHID string, userPHIDs ...string) (*Project, *Response, error) {
+	return f.edit(ctx, projectPHID, RemoveMembers(userPHIDs...))
+}
+
+// Join makes the user the client authenticates as a member of a project.
+func (f *ProjectsServiceOp) Join(ctx context.Context, projectPHID string) (*Project, *Response, error) {
+	me, resp, err := f.client.Users.WhoAmI(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return f.AddMembers(ctx, projectPHID, me.PHID.String())
 }
 
-// Delete a Project (Not Available Yet!)
-func (f *ProjectsServiceOp) Delete(project *Project) (*Response, error) {
-	return nil, errors.New("Delete is not available for Projects")
+// Leave removes the user the client authenticates as from a project.
+func (f *ProjectsServiceOp) Leave(ctx context.Context, projectPHID string) (*Project, *Response, error) {
+	me, resp, err := f.client.Users.WhoAmI(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return f.RemoveMembers(ctx, projectPHID, me.PHID.String())
+}
+
+// edit applies transactions to a project and reads it back.
+func (f *ProjectsServiceOp) edit(ctx context.Context, projectPHID string, transactions ...Transaction) (*Project, *Response, error) {
+	result, resp, err := f.client.Edit(ctx, projectsEditMethod, projectPHID, transactions)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return f.getByPHID(ctx, result.Object.PHID)
+}
+
+// getByPHID reads a project and its members through project.search. It
+// returns an error if the project can't be found.
+func (f *ProjectsServiceOp) getByPHID(ctx context.Context, phid string) (*Project, *Response, error) {
+	projects, resp, err := f.Find(ctx, &ProjectFindRequest{
+		Constraints: &ProjectFindConstraints{PHIDs: []string{phid}},
+		Attachments: &ProjectFindAttachments{Members: true},
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(projects) < 1 {
+		return nil, resp, notFoundError(resp, "project %s was not found", phid)
+	}
+
+	return &projects[0], resp, err
+}
+
+// Find searches for projects (through project.search).
+func (f *ProjectsServiceOp) Find(ctx context.Context, findRequest *ProjectFindRequest) ([]Project, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", projectsSearchPath, findRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(ProjectSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Project
+	for _, data := range root.Result.Data {
+		list = append(list, data.toProject())
+	}
+	return list, resp, err
+}
+
+// FindPager returns a Pager that follows the project.search cursor through
+// every page of results. Items are Project values.
+func (f *ProjectsServiceOp) FindPager(ctx context.Context, findRequest *ProjectFindRequest) *Pager {
+	page := ProjectFindRequest{}
+	if findRequest != nil {
+		page = *findRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", projectsSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(ProjectSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toProject())
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// CreateMilestone creates the next milestone of a project, such as a new
+// sprint. Milestones are numbered by Phabricator.
+func (f *ProjectsServiceOp) CreateMilestone(ctx context.Context, parentPHID string, name string) (*Project, *Response, error) {
+	return f.edit(ctx, "", CreateMilestone(parentPHID), SetProjectName(name))
+}
+
+// CreateSubproject creates a project under another.
+func (f *ProjectsServiceOp) CreateSubproject(ctx context.Context, parentPHID string, name string) (*Project, *Response, error) {
+	return f.edit(ctx, "", CreateSubproject(parentPHID), SetProjectName(name))
+}
+
+// Tree returns a project with all of its subprojects and milestones, however
+// deeply nested. Subprojects come before milestones, which are in order.
+func (f *ProjectsServiceOp) Tree(ctx context.Context, projectPHID string) (*ProjectTree, *Response, error) {
+	root, resp, err := f.getByPHID(ctx, projectPHID)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	pager := f.FindPager(ctx, &ProjectFindRequest{
+		Constraints: &ProjectFindConstraints{Ancestors: []string{projectPHID}},
+		Attachments: &ProjectFindAttachments{Members: true},
+	})
+
+	children := map[string][]Project{}
+	for pager.Next() {
+		project := pager.Item().(Project)
+		children[project.Parent] = append(children[project.Parent], project)
+	}
+
+	if pager.Response() != nil {
+		resp = pager.Response()
+	}
+	if err := pager.Err(); err != nil {
+		return nil, resp, err
+	}
+
+	return buildProjectTree(*root, children), resp, nil
+}
+
+// buildProjectTree hangs the projects in children, keyed by parent PHID,
+// under project.
+func buildProjectTree(project Project, children map[string][]Project) *ProjectTree {
+	kids := children[project.PHID.String()]
+	sort.Sort(projectsInTreeOrder(kids))
+
+	tree := &ProjectTree{Project: project}
+	for _, child := range kids {
+		tree.Children = append(tree.Children, buildProjectTree(child, children))
+	}
+	return tree
+}
+
+// projectsInTreeOrder sorts subprojects by name, then milestones by number.
+type projectsInTreeOrder []Project
+
+func (p projectsInTreeOrder) Len() int      { return len(p) }
+func (p projectsInTreeOrder) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p projectsInTreeOrder) Less(i, j int) bool {
+	if p[i].Milestone != p[j].Milestone {
+		return p[i].Milestone < p[j].Milestone
+	}
+	return p[i].Name < p[j].Name
 }
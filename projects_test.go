@@ -14,10 +14,14 @@ func TestProjects_ListProjects(t *testing.T) {
 
 	mux.HandleFunc("/api/project.query", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
+		if r.PostFormValue("after") == "35" {
+			fmt.Fprint(w, `{"result":{"data":[],"slugMap":[],"cursor":{"limit":2,"after":null,"before":"35"}},"error_code":null,"error_info":null}`)
+			return
+		}
 		fmt.Fprint(w, `{"result":{"data":{"PHID-PROJ-1":{"id":"181","phid":"PHID-PROJ-1","name":"Project 1","profileImagePHID":"PHID-FILE-1","icon":"flag-checkered","color":"disabled","members":["PHID-USER-1","PHID-USER-2"],"slugs":["project_1"],"dateCreated":"1445305386","dateModified":"1446586132"},"PHID-PROJ-2":{"id":"2","phid":"PHID-PROJ-2","name":"Project 2","profileImagePHID":"PHID-FILE-2","icon":"umbrella","color":"disabled","members":["PHID-USER-1"],"slugs":["project_2"],"dateCreated":"1447804194","dateModified":"1448327625"}},"slugMap":[],"cursor":{"limit":2,"after":"35","before":null}},"error_code":null,"error_info":null}`)
 	})
 
-	projects, _, err := client.Projects.List(nil)
+	projects, _, err := client.Projects.List(ctx, nil)
 	if err != nil {
 		t.Errorf("Projects.List returned error: %v", err)
 	}
@@ -31,55 +35,56 @@ func TestProjects_ListProjects(t *testing.T) {
 	}
 }
 
-/*
 func TestProjects_ListProjectsMultiplePages(t *testing.T) {
 	setup()
 	defer teardown()
 
 	mux.HandleFunc("/api/project.query", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
-		fmt.Fprint(w, `{"results": [{"region":{"slug":"nyc3"},"droplet":{"id":1},"ip":"192.168.0.1"},{"region":{"slug":"nyc3"},"droplet":{"id":2},"ip":"192.168.0.2"}], "links":{"pages":{"next":"http://example.com/v2/floating_ips/?page=2"}}}`)
+		switch r.PostFormValue("after") {
+		case "":
+			fmt.Fprint(w, `{"result":{"data":{"PHID-PROJ-1":{"id":"1","phid":"PHID-PROJ-1","name":"Project 1","icon":"flag-checkered","color":"disabled","members":[],"slugs":[]}},"slugMap":[],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
+		case "1":
+			fmt.Fprint(w, `{"result":{"data":{"PHID-PROJ-2":{"id":"2","phid":"PHID-PROJ-2","name":"Project 2","icon":"umbrella","color":"disabled","members":[],"slugs":[]}},"slugMap":[],"cursor":{"limit":1,"after":"2","before":"1"}},"error_code":null,"error_info":null}`)
+		case "2":
+			fmt.Fprint(w, `{"result":{"data":[],"slugMap":[],"cursor":{"limit":1,"after":null,"before":"2"}},"error_code":null,"error_info":null}`)
+		default:
+			t.Errorf("Unexpected cursor %q", r.PostFormValue("after"))
+		}
 	})
 
-	_, resp, err := client.Projects.List(nil)
+	projects, _, err := client.Projects.List(ctx, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	checkCurrentPage(t, resp, 1)
+	if len(projects) != 2 || projects[0].PHID != "PHID-PROJ-1" || projects[1].PHID != "PHID-PROJ-2" {
+		t.Errorf("Projects.List returned %+v, expected both pages", projects)
+	}
 }
 
-func TestProjects_RetrievePageByNumber(t *testing.T) {
+func TestProjects_ListProjectsMaxResults(t *testing.T) {
 	setup()
 	defer teardown()
 
-	jBlob := `
-	{
-		"floating_ips": [{"region":{"slug":"nyc3"},"droplet":{"id":1},"ip":"192.168.0.1"},{"region":{"slug":"nyc3"},"droplet":{"id":2},"ip":"192.168.0.2"}],
-		"links":{
-			"pages":{
-				"next":"http://example.com/v2/floating_ips/?page=3",
-				"prev":"http://example.com/v2/floating_ips/?page=1",
-				"last":"http://example.com/v2/floating_ips/?page=3",
-				"first":"http://example.com/v2/floating_ips/?page=1"
-			}
-		}
-	}`
-
+	requests := 0
 	mux.HandleFunc("/api/project.query", func(w http.ResponseWriter, r *http.Request) {
-		testMethod(t, r, "POST")
-		fmt.Fprint(w, jBlob)
+		requests++
+		if limit := r.URL.Query().Get("limit"); limit != "1" {
+			t.Errorf("Requested a page of %q projects, expected 1", limit)
+		}
+		fmt.Fprint(w, `{"result":{"data":{"PHID-PROJ-1":{"id":"1","phid":"PHID-PROJ-1","name":"Project 1"}},"slugMap":[],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
 	})
 
-	opt := &ListOptions{Page: 2}
-	_, resp, err := client.Projects.List(opt)
+	projects, _, err := client.Projects.List(ctx, &ListOptions{MaxResults: 1})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	checkCurrentPage(t, resp, 2)
+	if len(projects) != 1 || requests != 1 {
+		t.Errorf("Projects.List returned %d projects in %d requests, expected 1 in 1", len(projects), requests)
+	}
 }
-*/
 
 func TestProjects_Get(t *testing.T) {
 	setup()
@@ -91,7 +96,7 @@ func TestProjects_Get(t *testing.T) {
 		fmt.Fprint(w, `{"result":{"data":{"PHID-PROJ-1":{"id":"181","phid":"PHID-PROJ-1","name":"Project 1","profileImagePHID":"PHID-FILE-1","icon":"flag-checkered","color":"disabled","members":["PHID-USER-1","PHID-USER-2"],"slugs":["project_1"],"dateCreated":"1445305386","dateModified":"1446586132"}},"slugMap":[],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
 	})
 
-	project, _, err := client.Projects.Get("Project 1")
+	project, _, err := client.Projects.Get(ctx, "Project 1")
 	if err != nil {
 		t.Errorf("Projects.Get returned error: %v", err)
 	}
@@ -123,7 +128,7 @@ func TestProjects_Create(t *testing.T) {
 		fmt.Fprint(w, `{"result":{"id":1,"phid":"PHID-PROJ-1234","name":"Test Project 1","profileImagePHID":null,"icon":"umbrella","color":"violet","members":[],"slugs":[],"dateCreated":1451319026,"dateModified":1451319026},"error_code":null,"error_info":null}`)
 	})
 
-	project, _, err := client.Projects.Create(createRequest)
+	project, _, err := client.Projects.Create(ctx, createRequest)
 	if err != nil {
 		t.Errorf("Project.Create returned error: %v", err)
 	}
@@ -133,3 +138,258 @@ func TestProjects_Create(t *testing.T) {
 		t.Errorf("Projects.Create returned %+v, expected %+v", project, expected)
 	}
 }
+
+// projectSearchJSON is what project.search returns for PHID-PROJ-1 with its
+// members attached.
+const projectSearchJSON = `{"result":{"data":[{"id":1,"type":"PROJ","phid":"PHID-PROJ-1","fields":{"name":"Golph","slug":"golph","milestone":null,"depth":0,"parent":null,"icon":{"key":"umbrella","name":"Umbrella","icon":"fa-umbrella"},"color":{"key":"blue","name":"Blue"},"description":"Go Library for Phabricator","dateCreated":1445305386,"dateModified":1446586132,"policy":{"view":"users","edit":"users","join":"users"}},"attachments":{"members":{"members":[{"phid":"PHID-USER-1"},{"phid":"PHID-USER-2"}]}}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`
+
+var projectSearchExpected = &Project{
+	ID:          1,
+	PHID:        "PHID-PROJ-1",
+	Name:        "Golph",
+	Tags:        []string{"golph"},
+	Members:     []string{"PHID-USER-1", "PHID-USER-2"},
+	Icon:        "umbrella",
+	Color:       "blue",
+	Description: "Go Library for Phabricator",
+}
+
+// handleProjectEdit checks that project.edit is called with the expected
+// params and serves the project back through project.search.
+func handleProjectEdit(t *testing.T, expected string) {
+	mux.HandleFunc("/api/project.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, expected)
+		fmt.Fprint(w, `{"result":{"object":{"id":1,"phid":"PHID-PROJ-1"},"transactions":[{"phid":"PHID-XACT-PROJ-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"phids":["PHID-PROJ-1"]},"attachments":{"members":true}}`)
+		fmt.Fprint(w, projectSearchJSON)
+	})
+}
+
+func TestProjects_Update(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleProjectEdit(t, `{"objectIdentifier":"PHID-PROJ-1","transactions":[{"type":"name","value":"Golph"},{"type":"slugs","value":["golph"]},{"type":"icon","value":"umbrella"},{"type":"color","value":"blue"},{"type":"description","value":"Go Library for Phabricator"}]}`)
+
+	project, _, err := client.Projects.Update(ctx, &ProjectUpdateRequest{
+		PHID:        "PHID-PROJ-1",
+		Name:        "Golph",
+		Tags:        []string{"golph"},
+		Icon:        "umbrella",
+		Color:       "blue",
+		Description: "Go Library for Phabricator",
+	})
+	if err != nil {
+		t.Fatalf("Projects.Update returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(project, projectSearchExpected) {
+		t.Errorf("Projects.Update returned %+v, expected %+v", project, projectSearchExpected)
+	}
+}
+
+func TestProjects_UpdateWithoutPHID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if _, _, err := client.Projects.Update(ctx, &ProjectUpdateRequest{Name: "Golph"}); err == nil {
+		t.Errorf("Projects.Update without a PHID returned no error")
+	}
+}
+
+func TestProjects_UpdateNotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.edit", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"object":{"id":1,"phid":"PHID-PROJ-1"},"transactions":[{"phid":"PHID-XACT-PROJ-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	project, _, err := client.Projects.Update(ctx, &ProjectUpdateRequest{PHID: "PHID-PROJ-1", Name: "Golph"})
+	if !IsNotFound(err) || project != nil {
+		t.Errorf("Projects.Update returned %+v, %v, expected a not found error", project, err)
+	}
+}
+
+func TestProjects_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"PHID-PROJ-1","transactions":[{"type":"status","value":"archived"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":1,"phid":"PHID-PROJ-1"},"transactions":[{"phid":"PHID-XACT-PROJ-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	if _, err := client.Projects.Delete(ctx, &Project{PHID: "PHID-PROJ-1"}); err != nil {
+		t.Errorf("Projects.Delete returned error: %v", err)
+	}
+}
+
+func TestProjects_Members(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleProjectEdit(t, `{"objectIdentifier":"PHID-PROJ-1","transactions":[{"type":"members.add","value":["PHID-USER-2"]}]}`)
+
+	project, _, err := client.Projects.AddMembers(ctx, "PHID-PROJ-1", "PHID-USER-2")
+	if err != nil {
+		t.Fatalf("Projects.AddMembers returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(project, projectSearchExpected) {
+		t.Errorf("Projects.AddMembers returned %+v, expected %+v", project, projectSearchExpected)
+	}
+}
+
+func TestProjects_Leave(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"phid":"PHID-USER-3","userName":"carol"},"error_code":null,"error_info":null}`)
+	})
+
+	handleProjectEdit(t, `{"objectIdentifier":"PHID-PROJ-1","transactions":[{"type":"members.remove","value":["PHID-USER-3"]}]}`)
+
+	if _, _, err := client.Projects.Leave(ctx, "PHID-PROJ-1"); err != nil {
+		t.Errorf("Projects.Leave returned error: %v", err)
+	}
+}
+
+func TestProjects_Find(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"phids":["PHID-PROJ-3"]},"attachments":{"ancestors":true}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":3,"type":"PROJ","phid":"PHID-PROJ-3","fields":{"name":"Sprint 1","slug":null,"milestone":1,"depth":2,"parent":{"id":2,"phid":"PHID-PROJ-2","name":"Backend"},"icon":{"key":"release"},"color":{"key":"blue"},"description":""},"attachments":{"ancestors":{"ancestors":[{"id":2,"phid":"PHID-PROJ-2","name":"Backend"},{"id":1,"phid":"PHID-PROJ-1","name":"Golph"}]}}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`)
+	})
+
+	projects, _, err := client.Projects.Find(ctx, &ProjectFindRequest{
+		Constraints: &ProjectFindConstraints{PHIDs: []string{"PHID-PROJ-3"}},
+		Attachments: &ProjectFindAttachments{Ancestors: true},
+	})
+	if err != nil {
+		t.Fatalf("Projects.Find returned error: %v", err)
+	}
+
+	expected := []Project{{
+		ID:        3,
+		PHID:      "PHID-PROJ-3",
+		Name:      "Sprint 1",
+		Icon:      "release",
+		Color:     "blue",
+		Parent:    "PHID-PROJ-2",
+		Milestone: 1,
+		Depth:     2,
+		Ancestors: []string{"PHID-PROJ-2", "PHID-PROJ-1"},
+	}}
+	if !reflect.DeepEqual(projects, expected) {
+		t.Errorf("Projects.Find returned %+v, expected %+v", projects, expected)
+	}
+
+	if !projects[0].IsMilestone() {
+		t.Errorf("Project.IsMilestone() = false, expected true")
+	}
+}
+
+func TestProjects_CreateMilestone(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"transactions":[{"type":"milestone","value":"PHID-PROJ-1"},{"type":"name","value":"Sprint 1"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":3,"phid":"PHID-PROJ-3"},"transactions":[{"phid":"PHID-XACT-PROJ-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"constraints":{"phids":["PHID-PROJ-3"]},"attachments":{"members":true}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":3,"type":"PROJ","phid":"PHID-PROJ-3","fields":{"name":"Sprint 1","milestone":1,"depth":1,"parent":{"id":1,"phid":"PHID-PROJ-1","name":"Golph"}},"attachments":{"members":{"members":[]}}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	project, _, err := client.Projects.CreateMilestone(ctx, "PHID-PROJ-1", "Sprint 1")
+	if err != nil {
+		t.Fatalf("Projects.CreateMilestone returned error: %v", err)
+	}
+
+	expected := &Project{ID: 3, PHID: "PHID-PROJ-3", Name: "Sprint 1", Parent: "PHID-PROJ-1", Milestone: 1, Depth: 1}
+	if !reflect.DeepEqual(project, expected) {
+		t.Errorf("Projects.CreateMilestone returned %+v, expected %+v", project, expected)
+	}
+}
+
+func TestProjects_CreateSubproject(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.edit", func(w http.ResponseWriter, r *http.Request) {
+		testJSONParams(t, r, `{"transactions":[{"type":"parent","value":"PHID-PROJ-1"},{"type":"name","value":"Backend"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":2,"phid":"PHID-PROJ-2"},"transactions":[]},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[{"id":2,"type":"PROJ","phid":"PHID-PROJ-2","fields":{"name":"Backend","milestone":null,"depth":1,"parent":{"id":1,"phid":"PHID-PROJ-1","name":"Golph"}},"attachments":{}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	project, _, err := client.Projects.CreateSubproject(ctx, "PHID-PROJ-1", "Backend")
+	if err != nil {
+		t.Fatalf("Projects.CreateSubproject returned error: %v", err)
+	}
+
+	if project.Parent != "PHID-PROJ-1" || project.IsMilestone() {
+		t.Errorf("Projects.CreateSubproject returned %+v, expected a subproject of PHID-PROJ-1", project)
+	}
+}
+
+func TestProjects_Tree(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/project.search", func(w http.ResponseWriter, r *http.Request) {
+		params := conduitParams(t, r)
+		if _, ok := params["constraints"].(map[string]interface{})["ancestors"]; !ok {
+			fmt.Fprint(w, projectSearchJSON)
+			return
+		}
+
+		testJSONParams(t, r, `{"constraints":{"ancestors":["PHID-PROJ-1"]},"attachments":{"members":true}}`)
+		fmt.Fprint(w, `{"result":{"data":[
+			{"id":5,"phid":"PHID-PROJ-5","fields":{"name":"Sprint 2","milestone":2,"depth":1,"parent":{"phid":"PHID-PROJ-1"}}},
+			{"id":4,"phid":"PHID-PROJ-4","fields":{"name":"Sprint 1","milestone":1,"depth":1,"parent":{"phid":"PHID-PROJ-1"}}},
+			{"id":3,"phid":"PHID-PROJ-3","fields":{"name":"Sprint 1","milestone":1,"depth":2,"parent":{"phid":"PHID-PROJ-2"}}},
+			{"id":2,"phid":"PHID-PROJ-2","fields":{"name":"Backend","milestone":null,"depth":1,"parent":{"phid":"PHID-PROJ-1"}}}
+		],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	tree, _, err := client.Projects.Tree(ctx, "PHID-PROJ-1")
+	if err != nil {
+		t.Fatalf("Projects.Tree returned error: %v", err)
+	}
+
+	var walked []string
+	tree.Walk(func(project Project, depth int) error {
+		walked = append(walked, fmt.Sprintf("%d:%s", depth, project.PHID))
+		return nil
+	})
+
+	expected := []string{"0:PHID-PROJ-1", "1:PHID-PROJ-2", "2:PHID-PROJ-3", "1:PHID-PROJ-4", "1:PHID-PROJ-5"}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("ProjectTree.Walk visited %v, expected %v", walked, expected)
+	}
+
+	if milestones := tree.Milestones(); len(milestones) != 2 || milestones[0].Name != "Sprint 1" {
+		t.Errorf("ProjectTree.Milestones() returned %+v, expected Sprint 1 and Sprint 2", milestones)
+	}
+}
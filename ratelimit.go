@@ -0,0 +1,212 @@
+package golph
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces the requests a Client sends with a token bucket and caps
+// how many are in flight at once. Set it on Client.RateLimiter; as every
+// service shares the client, the limits apply to all of them together.
+//
+// When Phabricator turns a request away for being over its rate limit, the
+// limiter halves its rate and sends the request again instead of failing.
+// The rate then creeps back up with every request that gets through.
+type RateLimiter struct {
+	// MaxRateLimitRetries is how many times a rate limited request is sent
+	// again before its error is returned.
+	MaxRateLimitRetries int
+
+	mu          sync.Mutex
+	limit       float64 // configured requests per second, zero for no limit
+	rate        float64 // current requests per second, lowered while throttled
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+
+	inFlight chan struct{}
+}
+
+// minRateFraction is how far below its configured rate a limiter slows down.
+const minRateFraction = 1.0 / 16
+
+// NewRateLimiter returns a limiter allowing requestsPerSecond requests on
+// average, up to burst at once after a quiet spell, with at most maxInFlight
+// awaiting a response. Zero requestsPerSecond or maxInFlight leaves that
+// dimension unlimited.
+func NewRateLimiter(requestsPerSecond float64, burst, maxInFlight int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &RateLimiter{
+		MaxRateLimitRetries: 5,
+		limit:               requestsPerSecond,
+		rate:                requestsPerSecond,
+		burst:               float64(burst),
+		tokens:              float64(burst),
+	}
+
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+
+	return l
+}
+
+// Rate returns the requests per second the limiter currently allows, which
+// is below the configured rate while it recovers from being rate limited.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// Wait blocks until a request may be sent, or ctx is done. The returned
+// function must be called once the request has completed.
+func (l *RateLimiter) Wait(ctx context.Context) (func(), error) {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+
+	for {
+		wait := l.reserve(time.Now())
+		if wait <= 0 {
+			return release, nil
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+}
+
+// reserve takes a token if one is available at now, otherwise it returns how
+// long until one will be.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// throttle slows the limiter down after a rate limited response, pausing all
+// requests for as long as resp's Retry-After header asks.
+func (l *RateLimiter) throttle(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit > 0 {
+		l.rate /= 2
+		if min := l.limit * minRateFraction; l.rate < min {
+			l.rate = min
+		}
+		l.tokens = 0
+	}
+
+	pause := time.Second
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			pause = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if until := time.Now().Add(pause); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// speedUp moves a throttled limiter back toward its configured rate after a
+// request got through.
+func (l *RateLimiter) speedUp() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate < l.limit {
+		l.rate += l.limit * minRateFraction
+		if l.rate > l.limit {
+			l.rate = l.limit
+		}
+	}
+}
+
+// doLimited sends req through the client's RateLimiter, if it has one,
+// sending it again when Phabricator says it is over the rate limit. That is
+// safe whatever the method, as a rate limited request is turned away
+// unprocessed.
+func (c *Client) doLimited(req *http.Request, v interface{}) (*Response, error) {
+	l := c.RateLimiter
+	if l == nil {
+		return c.do(req, v)
+	}
+
+	body, err := bufferBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for retries := 0; ; retries++ {
+		release, err := l.Wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		response, err := c.do(req, v)
+		release()
+
+		if !IsRateLimited(err) {
+			l.speedUp()
+			return response, err
+		}
+
+		var resp *http.Response
+		if response != nil {
+			resp = response.Response
+		}
+		l.throttle(resp)
+
+		if retries >= l.MaxRateLimitRetries {
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package golph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	l := NewRateLimiter(10, 2, 0)
+	now := time.Now()
+
+	// The burst is available straight away, then a token every 100ms.
+	for i := 0; i < 2; i++ {
+		if wait := l.reserve(now); wait != 0 {
+			t.Errorf("reserve %d waited %v, expected the burst to be free", i, wait)
+		}
+	}
+
+	if wait := l.reserve(now); wait != 100*time.Millisecond {
+		t.Errorf("reserve after the burst waited %v, expected 100ms", wait)
+	}
+
+	if wait := l.reserve(now.Add(100 * time.Millisecond)); wait != 0 {
+		t.Errorf("reserve after 100ms waited %v, expected a refilled token", wait)
+	}
+}
+
+func TestRateLimiter_MaxInFlight(t *testing.T) {
+	l := NewRateLimiter(0, 1, 1)
+
+	release, err := l.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.Wait(timeout); err != context.DeadlineExceeded {
+		t.Errorf("Wait with a request in flight returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+
+	release, err = l.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait after release returned %v", err)
+	}
+	release()
+}
+
+func TestRateLimiter_SlowsDownWhenRateLimited(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.RateLimiter = NewRateLimiter(1000, 10, 4)
+
+	attempts := 0
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		testJSONParams(t, r, `{"transactions":[{"type":"title","value":"Once"}]}`)
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "TOO MANY REQUESTS", http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			fmt.Fprint(w, `{"result":null,"error_code":"ERR-CONDUIT-CORE","error_info":"You are performing too many actions too quickly."}`)
+		default:
+			fmt.Fprint(w, `{"result":{"object":{"id":1,"phid":"PHID-TASK-1"},"transactions":[]},"error_code":null,"error_info":null}`)
+		}
+	})
+
+	result, _, err := client.Tasks.Edit(ctx, &TaskEditRequest{Transactions: []Transaction{SetTitle("Once")}})
+	if err != nil {
+		t.Fatalf("Tasks.Edit returned error: %v", err)
+	}
+
+	if result.Object.PHID != "PHID-TASK-1" || attempts != 3 {
+		t.Errorf("Tasks.Edit returned %+v after %d attempts, expected PHID-TASK-1 after 3", result, attempts)
+	}
+
+	// Halved twice, then sped up once by the request that got through.
+	if rate := client.RateLimiter.Rate(); rate != 312.5 {
+		t.Errorf("RateLimiter.Rate() = %v, expected 312.5", rate)
+	}
+}
+
+func TestRateLimiter_GivesUp(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.RateLimiter = NewRateLimiter(0, 1, 0)
+	client.RateLimiter.MaxRateLimitRetries = 2
+
+	attempts := 0
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "TOO MANY REQUESTS", http.StatusTooManyRequests)
+	})
+
+	_, _, err := client.Users.WhoAmI(ctx)
+	if !IsRateLimited(err) || attempts != 3 {
+		t.Errorf("Users.WhoAmI returned %v after %d attempts, expected a rate limit error after 3", err, attempts)
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{&ErrorResponse{Response: &http.Response{StatusCode: http.StatusTooManyRequests}}, true},
+		{&ErrorResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}}, false},
+		{&ConduitError{Code: "ERR-RATE-LIMIT"}, true},
+		{&ConduitError{Code: "ERR-CONDUIT-CORE", Info: "You are performing too many actions too quickly."}, true},
+		{&ConduitError{Code: "ERR-CONDUIT-CORE", Info: "Unknown project."}, false},
+		{nil, false},
+	}
+
+	for _, c := range cases {
+		if got := IsRateLimited(c.err); got != c.expected {
+			t.Errorf("IsRateLimited(%v) = %v, expected %v", c.err, got, c.expected)
+		}
+	}
+}
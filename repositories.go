@@ -0,0 +1,573 @@
+package golph
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+const repositoriesSearchPath = "api/diffusion.repository.search"
+const commitsSearchPath = "api/diffusion.commit.search"
+const branchesQueryPath = "api/diffusion.branchquery"
+const tagsQueryPath = "api/diffusion.tagsquery"
+const browseQueryPath = "api/diffusion.browsequery"
+const fileContentQueryPath = "api/diffusion.filecontentquery"
+const historyQueryPath = "api/diffusion.historyquery"
+const fileDownloadPath = "api/file.download"
+
+// RepositoriesService is an interface for interfacing with repositories (Diffusion)
+// See: https://secure.phabricator.com/conduit/ (and search for diffusion)
+type RepositoriesService interface {
+	Search(context.Context, *RepositorySearchRequest) ([]Repository, *Response, error)
+	SearchCommits(context.Context, *CommitSearchRequest) ([]Commit, *Response, error)
+	SearchCommitsPager(context.Context, *CommitSearchRequest) *Pager
+	Branches(context.Context, *BranchQueryRequest) ([]RepositoryRef, *Response, error)
+	Tags(context.Context, *TagsQueryRequest) ([]RepositoryTag, *Response, error)
+	Browse(context.Context, *BrowseQueryRequest) (*BrowseResult, *Response, error)
+	FileContent(context.Context, *FileContentQueryRequest) ([]byte, *Response, error)
+	History(context.Context, *HistoryQueryRequest) (*HistoryResult, *Response, error)
+}
+
+// RepositoriesServiceOp handles communication with the conduit methods
+type RepositoriesServiceOp struct {
+	client *Client
+}
+
+var _ RepositoriesService = &RepositoriesServiceOp{}
+
+// Repository represents a Diffusion repository.
+type Repository struct {
+	ID            int        `json:"id"`
+	PHID          PHID       `json:"phid"`
+	Name          string     `json:"name"`
+	VCS           string     `json:"vcs"`
+	Callsign      string     `json:"callsign"`
+	ShortName     string     `json:"shortName"`
+	Status        string     `json:"status"`
+	DefaultBranch string     `json:"defaultBranch"`
+	Description   string     `json:"description"`
+	DateCreated   *Timestamp `json:"dateCreated,omitempty"`
+	DateModified  *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f Repository) String() string {
+	return Stringify(f)
+}
+
+// CommitIdentity is the author or committer of a Commit.
+type CommitIdentity struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Raw          string `json:"raw"`
+	Epoch        int64  `json:"epoch"`
+	IdentityPHID string `json:"identityPHID"`
+	UserPHID     string `json:"userPHID"`
+}
+
+// Commit represents a commit imported into Diffusion.
+type Commit struct {
+	ID              int            `json:"id"`
+	PHID            PHID           `json:"phid"`
+	Identifier      string         `json:"identifier"`
+	Repository      string         `json:"repositoryPHID"`
+	Author          CommitIdentity `json:"author"`
+	Committer       CommitIdentity `json:"committer"`
+	Message         string         `json:"message"`
+	AuditStatus     string         `json:"auditStatus"`
+	AuditStatusName string         `json:"auditStatusName"`
+	IsImported      bool           `json:"isImported"`
+	IsUnreachable   bool           `json:"isUnreachable"`
+}
+
+func (f Commit) String() string {
+	return Stringify(f)
+}
+
+var differentialRevisionLine = regexp.MustCompile(`(?m)^Differential Revision:\s*(?:\S*/)?(D\d+)\s*$`)
+
+// Revision returns the revision, such as "D123", the commit was landed from,
+// taken from the "Differential Revision:" line arcanist adds to commit
+// messages. It returns an empty string for commits made outside of review.
+func (f Commit) Revision() string {
+	match := differentialRevisionLine.FindStringSubmatch(f.Message)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// RepositoryRef is a branch as returned by diffusion.branchquery.
+type RepositoryRef struct {
+	ShortName        string `json:"shortName"`
+	CommitIdentifier string `json:"commitIdentifier"`
+	RefType          string `json:"refType"`
+}
+
+// RepositoryTag is a tag as returned by diffusion.tagsquery.
+type RepositoryTag struct {
+	Name             string `json:"name"`
+	CommitIdentifier string `json:"commitIdentifier"`
+	Description      string `json:"description"`
+	Author           string `json:"author"`
+	Epoch            int64  `json:"epoch"`
+	Type             string `json:"type"`
+}
+
+// BrowsePath is a file or directory as returned by diffusion.browsequery.
+type BrowsePath struct {
+	FullPath string `json:"fullPath"`
+	Path     string `json:"path"`
+	Hash     string `json:"hash"`
+	FileType int    `json:"fileType"`
+	FileSize int64  `json:"fileSize"`
+}
+
+// BrowseResult is the listing of a directory at a commit.
+type BrowseResult struct {
+	IsValidResults        bool         `json:"isValidResults"`
+	ReasonForEmptyResults string       `json:"reasonForEmptyResults"`
+	ExistedAtCommit       string       `json:"existedAtCommit"`
+	DeletedAtCommit       string       `json:"deletedAtCommit"`
+	Paths                 []BrowsePath `json:"paths"`
+}
+
+// PathChange is a single entry in the history of a path.
+type PathChange struct {
+	CommitIdentifier string `json:"commitIdentifier"`
+	Epoch            int64  `json:"epoch"`
+	ChangeType       int    `json:"changeType"`
+	FileType         int    `json:"fileType"`
+	Path             string `json:"path"`
+	TargetPath       string `json:"targetPath"`
+}
+
+// CommitParents holds the parents of commits, keyed by commit identifier.
+type CommitParents map[string][]string
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *CommitParents) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = CommitParents{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string][]string)(m))
+}
+
+// HistoryResult is the history of a path, newest change first.
+type HistoryResult struct {
+	PathChanges []PathChange  `json:"pathChanges"`
+	Parents     CommitParents `json:"parents"`
+}
+
+// RepositorySearchConstraints narrows down the results of diffusion.repository.search.
+type RepositorySearchConstraints struct {
+	IDs        []int    `json:"ids,omitempty"`
+	PHIDs      []string `json:"phids,omitempty"`
+	Callsigns  []string `json:"callsigns,omitempty"`
+	ShortNames []string `json:"shortNames,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	URIs       []string `json:"uris,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Query      string   `json:"query,omitempty"`
+}
+
+// RepositorySearchRequest represents a request to diffusion.repository.search.
+type RepositorySearchRequest struct {
+	QueryKey    string                       `json:"queryKey,omitempty"`
+	Constraints *RepositorySearchConstraints `json:"constraints,omitempty"`
+	Order       string                       `json:"order,omitempty"`
+	Before      string                       `json:"before,omitempty"`
+	After       string                       `json:"after,omitempty"`
+	Limit       int                          `json:"limit,omitempty"`
+}
+
+// CommitSearchConstraints narrows down the results of diffusion.commit.search.
+type CommitSearchConstraints struct {
+	IDs              []int    `json:"ids,omitempty"`
+	PHIDs            []string `json:"phids,omitempty"`
+	Repositories     []string `json:"repositories,omitempty"`
+	Identifiers      []string `json:"identifiers,omitempty"`
+	Authors          []string `json:"authors,omitempty"`
+	Auditors         []string `json:"auditors,omitempty"`
+	ResponsiblePHIDs []string `json:"responsiblePHIDs,omitempty"`
+	Statuses         []string `json:"statuses,omitempty"`
+	Unreachable      *bool    `json:"unreachable,omitempty"`
+	Query            string   `json:"query,omitempty"`
+}
+
+// CommitSearchRequest represents a request to diffusion.commit.search.
+type CommitSearchRequest struct {
+	QueryKey    string                   `json:"queryKey,omitempty"`
+	Constraints *CommitSearchConstraints `json:"constraints,omitempty"`
+	Order       string                   `json:"order,omitempty"`
+	Before      string                   `json:"before,omitempty"`
+	After       string                   `json:"after,omitempty"`
+	Limit       int                      `json:"limit,omitempty"`
+}
+
+// BranchQueryRequest represents a request to diffusion.branchquery. Repository
+// may be a PHID, callsign or ID.
+type BranchQueryRequest struct {
+	Repository string   `json:"repository"`
+	Patterns   []string `json:"patterns,omitempty"`
+	Closed     *bool    `json:"closed,omitempty"`
+	Offset     int      `json:"offset,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+}
+
+// TagsQueryRequest represents a request to diffusion.tagsquery.
+type TagsQueryRequest struct {
+	Repository   string   `json:"repository"`
+	Names        []string `json:"names,omitempty"`
+	Commit       string   `json:"commit,omitempty"`
+	NeedMessages bool     `json:"needMessages,omitempty"`
+	Offset       int      `json:"offset,omitempty"`
+	Limit        int      `json:"limit,omitempty"`
+}
+
+// BrowseQueryRequest represents a request to diffusion.browsequery.
+type BrowseQueryRequest struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// FileContentQueryRequest represents a request to diffusion.filecontentquery.
+type FileContentQueryRequest struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Commit     string `json:"commit,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Timeout    int    `json:"timeout,omitempty"`
+	ByteLimit  int    `json:"byteLimit,omitempty"`
+}
+
+// HistoryQueryRequest represents a request to diffusion.historyquery.
+type HistoryQueryRequest struct {
+	Repository string `json:"repository"`
+	Commit     string `json:"commit"`
+	Path       string `json:"path,omitempty"`
+	Against    string `json:"against,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+type fileDownloadRequest struct {
+	PHID string `json:"phid"`
+}
+
+type repositorySearchFields struct {
+	Name          string `json:"name"`
+	VCS           string `json:"vcs"`
+	Callsign      string `json:"callsign"`
+	ShortName     string `json:"shortName"`
+	Status        string `json:"status"`
+	DefaultBranch string `json:"defaultBranch"`
+	Description   struct {
+		Raw string `json:"raw"`
+	} `json:"description"`
+	DateCreated  *Timestamp `json:"dateCreated"`
+	DateModified *Timestamp `json:"dateModified"`
+}
+
+type repositorySearchData struct {
+	ID     int                    `json:"id"`
+	PHID   PHID                   `json:"phid"`
+	Fields repositorySearchFields `json:"fields"`
+}
+
+func (d repositorySearchData) toRepository() Repository {
+	return Repository{
+		ID:            d.ID,
+		PHID:          d.PHID,
+		Name:          d.Fields.Name,
+		VCS:           d.Fields.VCS,
+		Callsign:      d.Fields.Callsign,
+		ShortName:     d.Fields.ShortName,
+		Status:        d.Fields.Status,
+		DefaultBranch: d.Fields.DefaultBranch,
+		Description:   d.Fields.Description.Raw,
+		DateCreated:   d.Fields.DateCreated,
+		DateModified:  d.Fields.DateModified,
+	}
+}
+
+type RepositorySearchResult struct {
+	Data   []repositorySearchData `json:"data"`
+	Cursor PhabricatorCursor      `json:"cursor"`
+}
+
+type RepositorySearchResponse struct {
+	Result    RepositorySearchResult `json:"result"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+	ErrorInfo string                 `json:"error_info,omitempty"`
+}
+
+type commitSearchFields struct {
+	Identifier    string         `json:"identifier"`
+	Repository    string         `json:"repositoryPHID"`
+	Author        CommitIdentity `json:"author"`
+	Committer     CommitIdentity `json:"committer"`
+	Message       string         `json:"message"`
+	IsImported    bool           `json:"isImported"`
+	IsUnreachable bool           `json:"isUnreachable"`
+	AuditStatus   struct {
+		Value string `json:"value"`
+		Name  string `json:"name"`
+	} `json:"auditStatus"`
+}
+
+type commitSearchData struct {
+	ID     int                `json:"id"`
+	PHID   PHID               `json:"phid"`
+	Fields commitSearchFields `json:"fields"`
+}
+
+func (d commitSearchData) toCommit() Commit {
+	return Commit{
+		ID:              d.ID,
+		PHID:            d.PHID,
+		Identifier:      d.Fields.Identifier,
+		Repository:      d.Fields.Repository,
+		Author:          d.Fields.Author,
+		Committer:       d.Fields.Committer,
+		Message:         d.Fields.Message,
+		AuditStatus:     d.Fields.AuditStatus.Value,
+		AuditStatusName: d.Fields.AuditStatus.Name,
+		IsImported:      d.Fields.IsImported,
+		IsUnreachable:   d.Fields.IsUnreachable,
+	}
+}
+
+type CommitSearchResult struct {
+	Data   []commitSearchData `json:"data"`
+	Cursor PhabricatorCursor  `json:"cursor"`
+}
+
+type CommitSearchResponse struct {
+	Result    CommitSearchResult `json:"result"`
+	ErrorCode string             `json:"error_code,omitempty"`
+	ErrorInfo string             `json:"error_info,omitempty"`
+}
+
+type BranchQueryResponse struct {
+	Result    []RepositoryRef `json:"result"`
+	ErrorCode string          `json:"error_code,omitempty"`
+	ErrorInfo string          `json:"error_info,omitempty"`
+}
+
+type TagsQueryResponse struct {
+	Result    []RepositoryTag `json:"result"`
+	ErrorCode string          `json:"error_code,omitempty"`
+	ErrorInfo string          `json:"error_info,omitempty"`
+}
+
+type BrowseQueryResponse struct {
+	Result    BrowseResult `json:"result"`
+	ErrorCode string       `json:"error_code,omitempty"`
+	ErrorInfo string       `json:"error_info,omitempty"`
+}
+
+type FileContentQueryResult struct {
+	TooSlow  bool   `json:"tooSlow"`
+	TooHuge  bool   `json:"tooHuge"`
+	FilePHID string `json:"filePHID"`
+}
+
+type FileContentQueryResponse struct {
+	Result    FileContentQueryResult `json:"result"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+	ErrorInfo string                 `json:"error_info,omitempty"`
+}
+
+type FileDownloadResponse struct {
+	Result    string `json:"result"`
+	ErrorCode string `json:"error_code,omitempty"`
+	ErrorInfo string `json:"error_info,omitempty"`
+}
+
+type HistoryQueryResponse struct {
+	Result    HistoryResult `json:"result"`
+	ErrorCode string        `json:"error_code,omitempty"`
+	ErrorInfo string        `json:"error_info,omitempty"`
+}
+
+// Search for repositories (through diffusion.repository.search).
+func (f *RepositoriesServiceOp) Search(ctx context.Context, searchRequest *RepositorySearchRequest) ([]Repository, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", repositoriesSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(RepositorySearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Repository
+	for _, data := range root.Result.Data {
+		list = append(list, data.toRepository())
+	}
+	return list, resp, err
+}
+
+// SearchCommits searches for commits (through diffusion.commit.search).
+func (f *RepositoriesServiceOp) SearchCommits(ctx context.Context, searchRequest *CommitSearchRequest) ([]Commit, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", commitsSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(CommitSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []Commit
+	for _, data := range root.Result.Data {
+		list = append(list, data.toCommit())
+	}
+	return list, resp, err
+}
+
+// SearchCommitsPager returns a Pager that follows the diffusion.commit.search
+// cursor through every page of results. Items are Commit values.
+func (f *RepositoriesServiceOp) SearchCommitsPager(ctx context.Context, searchRequest *CommitSearchRequest) *Pager {
+	page := CommitSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", commitsSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(CommitSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toCommit())
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// Branches lists the branches of a repository (through diffusion.branchquery).
+func (f *RepositoriesServiceOp) Branches(ctx context.Context, queryRequest *BranchQueryRequest) ([]RepositoryRef, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", branchesQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BranchQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return root.Result, resp, err
+}
+
+// Tags lists the tags of a repository (through diffusion.tagsquery).
+func (f *RepositoriesServiceOp) Tags(ctx context.Context, queryRequest *TagsQueryRequest) ([]RepositoryTag, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", tagsQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(TagsQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return root.Result, resp, err
+}
+
+// Browse lists a directory of a repository at a commit (through diffusion.browsequery).
+func (f *RepositoriesServiceOp) Browse(ctx context.Context, queryRequest *BrowseQueryRequest) (*BrowseResult, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", browseQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(BrowseQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
+
+// FileContent reads a file of a repository at a commit. Diffusion stores the
+// content as a file (through diffusion.filecontentquery), which is then
+// downloaded (through file.download).
+func (f *RepositoriesServiceOp) FileContent(ctx context.Context, queryRequest *FileContentQueryRequest) ([]byte, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", fileContentQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(FileContentQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	switch {
+	case root.Result.TooSlow:
+		return nil, resp, errors.New("file content query timed out")
+	case root.Result.TooHuge:
+		return nil, resp, errors.New("file is larger than the byte limit")
+	case root.Result.FilePHID == "":
+		return nil, resp, errors.New("file content query returned no file")
+	}
+
+	req, err = f.client.NewJSONRequest(ctx, "POST", fileDownloadPath, &fileDownloadRequest{PHID: root.Result.FilePHID})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	download := new(FileDownloadResponse)
+	resp, err = f.client.Do(req, download)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	content, err := base64.StdEncoding.DecodeString(download.Result)
+	return content, resp, err
+}
+
+// History returns the history of a path (through diffusion.historyquery).
+func (f *RepositoriesServiceOp) History(ctx context.Context, queryRequest *HistoryQueryRequest) (*HistoryResult, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", historyQueryPath, queryRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(HistoryQueryResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
@@ -0,0 +1,239 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepositories_Search(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.repository.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"callsigns":["GOLPH"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":1,"type":"REPO","phid":"PHID-REPO-1","fields":{"name":"golph","vcs":"git","callsign":"GOLPH","shortName":"golph","status":"active","isImporting":false,"spacePHID":null,"dateCreated":1451337180,"dateModified":1451337280,"policy":{"view":"users"},"defaultBranch":"master","description":{"raw":"Go Library for Phabricator"}},"attachments":{}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`)
+	})
+
+	repositories, _, err := client.Repositories.Search(ctx, &RepositorySearchRequest{
+		Constraints: &RepositorySearchConstraints{Callsigns: []string{"GOLPH"}},
+	})
+	if err != nil {
+		t.Fatalf("Repositories.Search returned error: %v", err)
+	}
+
+	expected := []Repository{
+		{
+			ID:            1,
+			PHID:          "PHID-REPO-1",
+			Name:          "golph",
+			VCS:           "git",
+			Callsign:      "GOLPH",
+			ShortName:     "golph",
+			Status:        "active",
+			DefaultBranch: "master",
+			Description:   "Go Library for Phabricator",
+			DateCreated:   &Timestamp{time.Unix(1451337180, 0)},
+			DateModified:  &Timestamp{time.Unix(1451337280, 0)},
+		},
+	}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Repositories.Search returned:\n%+v\nExpected:\n%+v", repositories, expected)
+	}
+}
+
+func TestRepositories_SearchCommits(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.commit.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"repositories":["PHID-REPO-1"],"identifiers":["abc123"],"authors":["PHID-USER-1"],"statuses":["needs-audit"]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":5,"type":"CMIT","phid":"PHID-CMIT-1","fields":{"identifier":"abc123","repositoryPHID":"PHID-REPO-1","author":{"name":"Alice","email":"alice@example.com","raw":"Alice <alice@example.com>","epoch":1451337180,"identityPHID":"PHID-IDNT-1","userPHID":"PHID-USER-1"},"committer":{"name":"Alice","email":"alice@example.com","raw":"Alice <alice@example.com>","epoch":1451337180,"identityPHID":"PHID-IDNT-1","userPHID":"PHID-USER-1"},"isImported":true,"isUnreachable":false,"auditStatus":{"value":"needs-audit","name":"Audit Required","closed":false,"color.ansi":"magenta"},"message":"Say hello\n\nSummary: Hi\n\nDifferential Revision: https://phabricator.example.com/D123","policy":{"view":"users"}},"attachments":{}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`)
+	})
+
+	commits, _, err := client.Repositories.SearchCommits(ctx, &CommitSearchRequest{
+		Constraints: &CommitSearchConstraints{
+			Repositories: []string{"PHID-REPO-1"},
+			Identifiers:  []string{"abc123"},
+			Authors:      []string{"PHID-USER-1"},
+			Statuses:     []string{"needs-audit"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Repositories.SearchCommits returned error: %v", err)
+	}
+
+	alice := CommitIdentity{Name: "Alice", Email: "alice@example.com", Raw: "Alice <alice@example.com>", Epoch: 1451337180, IdentityPHID: "PHID-IDNT-1", UserPHID: "PHID-USER-1"}
+	expected := []Commit{
+		{
+			ID:              5,
+			PHID:            "PHID-CMIT-1",
+			Identifier:      "abc123",
+			Repository:      "PHID-REPO-1",
+			Author:          alice,
+			Committer:       alice,
+			Message:         "Say hello\n\nSummary: Hi\n\nDifferential Revision: https://phabricator.example.com/D123",
+			AuditStatus:     "needs-audit",
+			AuditStatusName: "Audit Required",
+			IsImported:      true,
+		},
+	}
+	if !reflect.DeepEqual(commits, expected) {
+		t.Errorf("Repositories.SearchCommits returned:\n%+v\nExpected:\n%+v", commits, expected)
+	}
+
+	if commits[0].Revision() != "D123" {
+		t.Errorf("Commit.Revision() = %q, expected D123", commits[0].Revision())
+	}
+}
+
+func TestCommit_Revision(t *testing.T) {
+	cases := map[string]string{
+		"Say hello": "",
+		"Say hello\n\nDifferential Revision: D45":     "D45",
+		"Say hello\n\nDifferential Revision: D45\n\n": "D45",
+		"Mentions Differential Revision: D45 inline":  "",
+	}
+
+	for message, expected := range cases {
+		if got := (Commit{Message: message}).Revision(); got != expected {
+			t.Errorf("Commit{Message: %q}.Revision() = %q, expected %q", message, got, expected)
+		}
+	}
+}
+
+func TestRepositories_Branches(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.branchquery", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"repository":"GOLPH","patterns":["release/*"]}`)
+		fmt.Fprint(w, `{"result":[{"shortName":"release/1.0","commitIdentifier":"abc123","refType":"branch","rawFields":{"objectname":"abc123"}}],"error_code":null,"error_info":null}`)
+	})
+
+	branches, _, err := client.Repositories.Branches(ctx, &BranchQueryRequest{Repository: "GOLPH", Patterns: []string{"release/*"}})
+	if err != nil {
+		t.Fatalf("Repositories.Branches returned error: %v", err)
+	}
+
+	expected := []RepositoryRef{{ShortName: "release/1.0", CommitIdentifier: "abc123", RefType: "branch"}}
+	if !reflect.DeepEqual(branches, expected) {
+		t.Errorf("Repositories.Branches returned %+v, expected %+v", branches, expected)
+	}
+}
+
+func TestRepositories_Tags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.tagsquery", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"result":[{"name":"v0.1.0","commitIdentifier":"abc123","description":"First release","author":"Alice","epoch":1451337180,"type":"git/annotated"}],"error_code":null,"error_info":null}`)
+	})
+
+	tags, _, err := client.Repositories.Tags(ctx, &TagsQueryRequest{Repository: "GOLPH"})
+	if err != nil {
+		t.Fatalf("Repositories.Tags returned error: %v", err)
+	}
+
+	expected := []RepositoryTag{{Name: "v0.1.0", CommitIdentifier: "abc123", Description: "First release", Author: "Alice", Epoch: 1451337180, Type: "git/annotated"}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Repositories.Tags returned %+v, expected %+v", tags, expected)
+	}
+}
+
+func TestRepositories_Browse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.browsequery", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"repository":"GOLPH","commit":"abc123"}`)
+		fmt.Fprint(w, `{"result":{"isValidResults":true,"reasonForEmptyResults":null,"existedAtCommit":null,"deletedAtCommit":null,"paths":[{"fullPath":"golph.go","path":"golph.go","hash":"def456","fileType":7,"fileSize":1024}]},"error_code":null,"error_info":null}`)
+	})
+
+	browse, _, err := client.Repositories.Browse(ctx, &BrowseQueryRequest{Repository: "GOLPH", Commit: "abc123"})
+	if err != nil {
+		t.Fatalf("Repositories.Browse returned error: %v", err)
+	}
+
+	expected := &BrowseResult{
+		IsValidResults: true,
+		Paths:          []BrowsePath{{FullPath: "golph.go", Path: "golph.go", Hash: "def456", FileType: 7, FileSize: 1024}},
+	}
+	if !reflect.DeepEqual(browse, expected) {
+		t.Errorf("Repositories.Browse returned %+v, expected %+v", browse, expected)
+	}
+}
+
+func TestRepositories_FileContent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.filecontentquery", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"repository":"GOLPH","path":"README.md","commit":"abc123"}`)
+		fmt.Fprint(w, `{"result":{"tooSlow":false,"tooHuge":false,"filePHID":"PHID-FILE-1"},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/file.download", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"phid":"PHID-FILE-1"}`)
+		fmt.Fprint(w, `{"result":"IyBHb2xwaAo=","error_code":null,"error_info":null}`)
+	})
+
+	content, _, err := client.Repositories.FileContent(ctx, &FileContentQueryRequest{Repository: "GOLPH", Path: "README.md", Commit: "abc123"})
+	if err != nil {
+		t.Fatalf("Repositories.FileContent returned error: %v", err)
+	}
+
+	if string(content) != "# Golph\n" {
+		t.Errorf("Repositories.FileContent returned %q, expected %q", content, "# Golph\n")
+	}
+}
+
+func TestRepositories_History(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.historyquery", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"result":{"pathChanges":[{"commitIdentifier":"abc123","epoch":1451337180,"changeType":2,"fileType":7,"path":"README.md","targetPath":null}],"parents":{"abc123":["def456"]}},"error_code":null,"error_info":null}`)
+	})
+
+	history, _, err := client.Repositories.History(ctx, &HistoryQueryRequest{Repository: "GOLPH", Commit: "master", Path: "README.md"})
+	if err != nil {
+		t.Fatalf("Repositories.History returned error: %v", err)
+	}
+
+	expected := &HistoryResult{
+		PathChanges: []PathChange{{CommitIdentifier: "abc123", Epoch: 1451337180, ChangeType: 2, FileType: 7, Path: "README.md"}},
+		Parents:     CommitParents{"abc123": {"def456"}},
+	}
+	if !reflect.DeepEqual(history, expected) {
+		t.Errorf("Repositories.History returned %+v, expected %+v", history, expected)
+	}
+}
+
+func TestRepositories_HistoryWithoutParents(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/diffusion.historyquery", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"pathChanges":[],"parents":[]},"error_code":null,"error_info":null}`)
+	})
+
+	history, _, err := client.Repositories.History(ctx, &HistoryQueryRequest{Repository: "GOLPH", Commit: "master", Path: "README.md"})
+	if err != nil {
+		t.Fatalf("Repositories.History returned error: %v", err)
+	}
+
+	if history.Parents == nil || len(history.Parents) != 0 {
+		t.Errorf("Repositories.History returned parents %#v, expected none", history.Parents)
+	}
+}
@@ -0,0 +1,239 @@
+package golph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PHIDResolver turns PHIDs into the objects behind them, such as the names of
+// a task's author, owner and projects. Lookups made around the same time, from
+// any number of goroutines, are gathered into a single phid.query call, and
+// what comes back is remembered for TTL so it isn't asked for again. The zero
+// value is usable once PHIDs is set, with no batch window and no batch limit.
+type PHIDResolver struct {
+	// PHIDs looks the PHIDs up, such as Client.PHIDs.
+	PHIDs PHIDsService
+
+	// TTL is how long a resolved PHID is remembered. Zero remembers it for
+	// the life of the resolver.
+	TTL time.Duration
+
+	// BatchWindow is how long a lookup waits for others to share its call.
+	BatchWindow time.Duration
+
+	// MaxBatch is the most PHIDs sent in one call. A full batch is sent at
+	// once, without waiting out the window.
+	MaxBatch int
+
+	mu    sync.Mutex
+	cache map[string]resolvedPHID
+	batch *phidBatch
+
+	// pending holds the batch each PHID waits on, until it has been looked up.
+	pending map[string]*phidBatch
+}
+
+type resolvedPHID struct {
+	info    *PHIDInfo
+	expires time.Time
+}
+
+// phidBatch is a set of PHIDs waiting to be sent to phid.query together. Its
+// call is cancelled once every caller waiting on it has given up.
+type phidBatch struct {
+	phids []string
+	seen  map[string]bool
+	once  sync.Once
+	done  chan struct{}
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+
+	result PHIDInfoMap
+	err    error
+}
+
+// NewPHIDResolver returns a resolver that looks PHIDs up through phids and
+// remembers them for ttl.
+func NewPHIDResolver(phids PHIDsService, ttl time.Duration) *PHIDResolver {
+	return &PHIDResolver{
+		TTL:         ttl,
+		BatchWindow: 10 * time.Millisecond,
+		MaxBatch:    500,
+		PHIDs:       phids,
+	}
+}
+
+// Resolve returns the object behind phid, or nil if it doesn't exist or can't
+// be seen.
+func (r *PHIDResolver) Resolve(ctx context.Context, phid string) (*PHIDInfo, error) {
+	resolved, err := r.ResolveAll(ctx, phid)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, ok := resolved[phid]; ok {
+		return &info, nil
+	}
+	return nil, nil
+}
+
+// ResolveAll returns the objects behind phids, keyed by PHID. PHIDs that don't
+// exist or can't be seen are left out. PHIDs already being looked up for
+// another caller are waited on rather than asked for again.
+func (r *PHIDResolver) ResolveAll(ctx context.Context, phids ...string) (PHIDInfoMap, error) {
+	resolved := PHIDInfoMap{}
+	now := time.Now()
+
+	r.mu.Lock()
+	var batches []*phidBatch
+	for _, phid := range phids {
+		if cached, ok := r.cache[phid]; ok && (cached.expires.IsZero() || now.Before(cached.expires)) {
+			if cached.info != nil {
+				resolved[phid] = *cached.info
+			}
+			continue
+		}
+
+		batch := r.pending[phid]
+		if batch == nil || batch.ctx.Err() != nil {
+			batch = r.enqueue(phid)
+		}
+
+		if !containsBatch(batches, batch) {
+			batch.waiters++
+			batches = append(batches, batch)
+		}
+	}
+	r.mu.Unlock()
+
+	defer r.leave(batches)
+
+	for _, batch := range batches {
+		select {
+		case <-batch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if batch.err != nil {
+			return nil, batch.err
+		}
+
+		for _, phid := range phids {
+			if info, ok := batch.result[phid]; ok {
+				resolved[phid] = info
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// Forget drops phids from the cache, so they are looked up afresh next time.
+func (r *PHIDResolver) Forget(phids ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, phid := range phids {
+		delete(r.cache, phid)
+	}
+}
+
+// enqueue adds phid to the pending batch, starting a new one if there is none,
+// and returns the batch it was added to. r.mu must be held.
+func (r *PHIDResolver) enqueue(phid string) *phidBatch {
+	batch := r.batch
+	if batch == nil || batch.ctx.Err() != nil {
+		batch = &phidBatch{seen: map[string]bool{}, done: make(chan struct{})}
+		batch.ctx, batch.cancel = context.WithCancel(context.Background())
+		r.batch = batch
+		time.AfterFunc(r.BatchWindow, func() { r.send(batch) })
+	}
+
+	if !batch.seen[phid] {
+		batch.seen[phid] = true
+		batch.phids = append(batch.phids, phid)
+	}
+
+	if r.pending == nil {
+		r.pending = map[string]*phidBatch{}
+	}
+	r.pending[phid] = batch
+
+	if r.MaxBatch > 0 && len(batch.phids) >= r.MaxBatch {
+		r.batch = nil
+		go r.send(batch)
+	}
+
+	return batch
+}
+
+// leave stops waiting on batches, cancelling the call of any batch no one is
+// waiting on any more.
+func (r *PHIDResolver) leave(batches []*phidBatch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, batch := range batches {
+		batch.waiters--
+		if batch.waiters == 0 {
+			batch.cancel()
+		}
+	}
+}
+
+func containsBatch(batches []*phidBatch, batch *phidBatch) bool {
+	for _, b := range batches {
+		if b == batch {
+			return true
+		}
+	}
+	return false
+}
+
+// send looks up a batch and wakes everyone waiting on it. The call runs on the
+// batch's own context, as several callers may be waiting on it.
+func (r *PHIDResolver) send(batch *phidBatch) {
+	batch.once.Do(func() {
+		r.mu.Lock()
+		if r.batch == batch {
+			r.batch = nil
+		}
+		r.mu.Unlock()
+
+		if batch.err = batch.ctx.Err(); batch.err == nil {
+			batch.result, _, batch.err = r.PHIDs.Query(batch.ctx, batch.phids...)
+		}
+
+		var expires time.Time
+		if r.TTL > 0 {
+			expires = time.Now().Add(r.TTL)
+		}
+
+		r.mu.Lock()
+		for _, phid := range batch.phids {
+			if r.pending[phid] == batch {
+				delete(r.pending, phid)
+			}
+
+			if batch.err != nil {
+				continue
+			}
+
+			entry := resolvedPHID{expires: expires}
+			if info, ok := batch.result[phid]; ok {
+				entry.info = &info
+			}
+			if r.cache == nil {
+				r.cache = map[string]resolvedPHID{}
+			}
+			r.cache[phid] = entry
+		}
+		r.mu.Unlock()
+
+		close(batch.done)
+	})
+}
@@ -0,0 +1,239 @@
+package golph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePHIDs answers phid.query from a fixed set of objects, recording the calls.
+// When block is set, calls wait for it to be closed, or for their context to
+// be done, after signalling started. Calls given up on close aborted.
+type fakePHIDs struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+
+	started chan struct{}
+	block   chan struct{}
+	aborted chan struct{}
+}
+
+func (f *fakePHIDs) Query(ctx context.Context, phids ...string) (PHIDInfoMap, *Response, error) {
+	f.mu.Lock()
+	sorted := append([]string(nil), phids...)
+	sort.Strings(sorted)
+	f.calls = append(f.calls, sorted)
+	err := f.err
+	f.mu.Unlock()
+
+	if f.block != nil {
+		f.started <- struct{}{}
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			if f.aborted != nil {
+				close(f.aborted)
+			}
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	infos := PHIDInfoMap{}
+	for _, phid := range phids {
+		if phid != "PHID-USER-404" {
+			infos[phid] = PHIDInfo{PHID: phid, Name: "name of " + phid}
+		}
+	}
+	return infos, nil, nil
+}
+
+func (f *fakePHIDs) Lookup(ctx context.Context, names ...string) (PHIDInfoMap, *Response, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (f *fakePHIDs) LookupMonogram(ctx context.Context, m Monogram) (interface{}, *Response, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func TestPHIDResolver_Batches(t *testing.T) {
+	phids := &fakePHIDs{}
+	resolver := NewPHIDResolver(phids, time.Minute)
+	resolver.BatchWindow = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			phid := fmt.Sprintf("PHID-USER-%d", i%5)
+			info, err := resolver.Resolve(ctx, phid)
+			if err != nil || info == nil || info.Name != "name of "+phid {
+				t.Errorf("Resolve(%q) returned %+v, %v", phid, info, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(phids.calls) != 1 || len(phids.calls[0]) != 5 {
+		t.Errorf("phid.query was called with %v, expected once with 5 PHIDs", phids.calls)
+	}
+
+	resolved, err := resolver.ResolveAll(ctx, "PHID-USER-1", "PHID-USER-404")
+	if err != nil {
+		t.Fatalf("ResolveAll returned error: %v", err)
+	}
+
+	if len(resolved) != 1 || resolved["PHID-USER-1"].Name != "name of PHID-USER-1" {
+		t.Errorf("ResolveAll returned %+v, expected only PHID-USER-1", resolved)
+	}
+
+	if len(phids.calls) != 2 || len(phids.calls[1]) != 1 || phids.calls[1][0] != "PHID-USER-404" {
+		t.Errorf("phid.query was called with %v, expected a second call for PHID-USER-404 only", phids.calls)
+	}
+
+	if info, err := resolver.Resolve(ctx, "PHID-USER-404"); info != nil || err != nil || len(phids.calls) != 2 {
+		t.Errorf("Resolve of a missing PHID returned %+v, %v after %d calls, expected it cached as missing", info, err, len(phids.calls))
+	}
+}
+
+func TestPHIDResolver_MaxBatch(t *testing.T) {
+	phids := &fakePHIDs{}
+	resolver := NewPHIDResolver(phids, time.Minute)
+	resolver.BatchWindow = time.Hour
+	resolver.MaxBatch = 2
+
+	resolved, err := resolver.ResolveAll(ctx, "PHID-USER-1", "PHID-USER-2", "PHID-USER-3", "PHID-USER-4")
+	if err != nil {
+		t.Fatalf("ResolveAll returned error: %v", err)
+	}
+
+	if len(resolved) != 4 || len(phids.calls) != 2 {
+		t.Errorf("ResolveAll returned %d PHIDs in %d calls, expected 4 in 2", len(resolved), len(phids.calls))
+	}
+}
+
+func TestPHIDResolver_TTL(t *testing.T) {
+	phids := &fakePHIDs{}
+	resolver := NewPHIDResolver(phids, time.Millisecond)
+	resolver.BatchWindow = 0
+
+	resolver.Resolve(ctx, "PHID-USER-1")
+	time.Sleep(5 * time.Millisecond)
+	resolver.Resolve(ctx, "PHID-USER-1")
+
+	resolver.TTL = time.Hour
+	resolver.Forget("PHID-USER-1")
+	resolver.Resolve(ctx, "PHID-USER-1")
+	resolver.Resolve(ctx, "PHID-USER-1")
+
+	if len(phids.calls) != 3 {
+		t.Errorf("phid.query was called %d times, expected 3", len(phids.calls))
+	}
+}
+
+func TestPHIDResolver_Error(t *testing.T) {
+	phids := &fakePHIDs{err: errors.New("boom")}
+	resolver := NewPHIDResolver(phids, time.Minute)
+	resolver.BatchWindow = 0
+
+	if _, err := resolver.Resolve(ctx, "PHID-USER-1"); err != phids.err {
+		t.Errorf("Resolve returned %v, expected %v", err, phids.err)
+	}
+
+	phids.err = nil
+	if info, err := resolver.Resolve(ctx, "PHID-USER-1"); err != nil || info == nil {
+		t.Errorf("Resolve after an error returned %+v, %v, expected the PHID looked up again", info, err)
+	}
+}
+
+func TestPHIDResolver_ZeroValue(t *testing.T) {
+	phids := &fakePHIDs{}
+	resolver := &PHIDResolver{PHIDs: phids}
+
+	if info, err := resolver.Resolve(ctx, "PHID-USER-1"); err != nil || info == nil || info.Name != "name of PHID-USER-1" {
+		t.Errorf("Resolve returned %+v, %v", info, err)
+	}
+
+	resolver.Resolve(ctx, "PHID-USER-1")
+	if len(phids.calls) != 1 {
+		t.Errorf("phid.query was called %d times, expected once", len(phids.calls))
+	}
+}
+
+func TestPHIDResolver_InFlight(t *testing.T) {
+	phids := &fakePHIDs{started: make(chan struct{}, 2), block: make(chan struct{})}
+	resolver := NewPHIDResolver(phids, time.Minute)
+	resolver.BatchWindow = 0
+
+	first := make(chan error)
+	go func() {
+		_, err := resolver.Resolve(ctx, "PHID-USER-1")
+		first <- err
+	}()
+	<-phids.started
+
+	second := make(chan PHIDInfoMap)
+	go func() {
+		resolved, err := resolver.ResolveAll(ctx, "PHID-USER-1", "PHID-USER-2")
+		if err != nil {
+			t.Errorf("ResolveAll returned error: %v", err)
+		}
+		second <- resolved
+	}()
+	<-phids.started
+
+	close(phids.block)
+	if err := <-first; err != nil {
+		t.Errorf("Resolve returned error: %v", err)
+	}
+	if resolved := <-second; len(resolved) != 2 {
+		t.Errorf("ResolveAll returned %+v, expected both PHIDs", resolved)
+	}
+
+	expected := [][]string{{"PHID-USER-1"}, {"PHID-USER-2"}}
+	if !reflect.DeepEqual(phids.calls, expected) {
+		t.Errorf("phid.query was called with %v, expected %v", phids.calls, expected)
+	}
+}
+
+func TestPHIDResolver_Cancel(t *testing.T) {
+	phids := &fakePHIDs{started: make(chan struct{}, 1), block: make(chan struct{}), aborted: make(chan struct{})}
+	resolver := NewPHIDResolver(phids, time.Minute)
+	resolver.BatchWindow = 0
+
+	cancelled, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, err := resolver.Resolve(cancelled, "PHID-USER-1")
+		done <- err
+	}()
+
+	<-phids.started
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Resolve returned %v, expected %v", err, context.Canceled)
+	}
+
+	// No one else was waiting, so the call is cancelled too.
+	select {
+	case <-phids.aborted:
+	case <-time.After(time.Second):
+		t.Fatalf("phid.query was not cancelled")
+	}
+
+	close(phids.block)
+	if info, err := resolver.Resolve(ctx, "PHID-USER-1"); err != nil || info == nil {
+		t.Errorf("Resolve after a cancelled call returned %+v, %v", info, err)
+	}
+}
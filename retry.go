@@ -0,0 +1,245 @@
+package golph
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Client.Do retries requests that fail transiently,
+// such as a load balancer answering 502 or 503. Set it on Client.RetryPolicy;
+// a nil policy never retries.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, counting the first.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry. It doubles with every
+	// further retry, up to MaxBackoff, and is jittered so that many clients
+	// don't retry in lock step.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// RetryStatuses are the HTTP status codes that are retried.
+	RetryStatuses []int
+
+	// RetryCodes are the Conduit error codes that are retried.
+	RetryCodes []string
+
+	// Idempotent reports whether a Conduit method can safely be sent twice.
+	// Methods that aren't are never retried, so a task is not created twice
+	// when the first response was lost. Defaults to IsIdempotentMethod.
+	Idempotent func(method string) bool
+
+	// OnRetry, if set, is called before waiting to retry a request.
+	OnRetry RetryObserver
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	// Conduit method being called, like maniphest.search
+	Method string
+
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt int
+
+	// Wait is how long the client sleeps before the next attempt.
+	Wait time.Duration
+
+	Request *http.Request
+
+	// Response of the failed attempt, nil if none was received
+	Response *http.Response
+
+	Err error
+}
+
+// RetryObserver is called with every retry a RetryPolicy makes.
+type RetryObserver func(*RetryEvent)
+
+// DefaultRetryPolicy returns a policy that makes up to four attempts, backing
+// off from half a second, on the statuses a proxy in front of Phabricator
+// returns while it is briefly unavailable or overloaded.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// IsIdempotentMethod reports whether sending the Conduit method twice has the
+// same effect as sending it once. Reads are idempotent; methods that create
+// objects, apply edits or add comments are not.
+func IsIdempotentMethod(method string) bool {
+	name := method
+	if i := strings.LastIndex(method, "."); i >= 0 {
+		name = method[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "create"):
+		return false
+	case name == "edit", name == "update", name == "sendmessage":
+		return false
+	}
+
+	return true
+}
+
+// conduitMethod returns the Conduit method an API request calls.
+func conduitMethod(req *http.Request) string {
+	return path.Base(req.URL.Path)
+}
+
+// allows reports whether req may be retried at all under the policy.
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	if p == nil || p.MaxAttempts <= 1 {
+		return false
+	}
+
+	idempotent := p.Idempotent
+	if idempotent == nil {
+		idempotent = IsIdempotentMethod
+	}
+
+	return idempotent(conduitMethod(req))
+}
+
+// retryable reports whether req, which failed with err, is worth retrying.
+func (p *RetryPolicy) retryable(req *http.Request, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	switch err := err.(type) {
+	case *url.Error:
+		// The request never got an answer. Only a timeout or a temporary
+		// network error, like a reset connection, is worth another try; a bad
+		// certificate or URL fails the same way every time.
+		if err.Err == context.Canceled || err.Err == context.DeadlineExceeded {
+			return false
+		}
+		return err.Timeout() || err.Temporary()
+	case *ErrorResponse:
+		for _, status := range p.RetryStatuses {
+			if err.Response.StatusCode == status {
+				return true
+			}
+		}
+	case *ConduitError:
+		for _, code := range p.RetryCodes {
+			if err.Code == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// backoff returns how long to wait after the given failed attempt. A
+// Retry-After header on resp is honored when it asks for longer.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	wait := p.MinBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+
+	// Sleep somewhere between half and all of the backoff.
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half+1))
+	}
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if after := time.Duration(seconds) * time.Second; after > wait {
+				wait = after
+			}
+		}
+	}
+
+	return wait
+}
+
+// doWithRetry sends req through send, retrying transient failures as the
+// policy allows. The request body is buffered so it can be replayed.
+func (c *Client) doWithRetry(req *http.Request, v interface{}, send func(*http.Request, interface{}) (*Response, error)) (*Response, error) {
+	policy := c.RetryPolicy
+	if !policy.allows(req) {
+		return send(req, v)
+	}
+
+	body, err := bufferBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		response, err := send(req, v)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(req, err) {
+			return response, err
+		}
+
+		var resp *http.Response
+		if response != nil {
+			resp = response.Response
+		}
+
+		wait := policy.backoff(attempt, resp)
+		if policy.OnRetry != nil {
+			policy.OnRetry(&RetryEvent{
+				Method:   conduitMethod(req),
+				Attempt:  attempt,
+				Wait:     wait,
+				Request:  req,
+				Response: resp,
+				Err:      err,
+			})
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return response, err
+		}
+	}
+}
+
+// bufferBody reads the body of req so it can be sent more than once.
+func bufferBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	return ioutil.ReadAll(req.Body)
+}
+
+// sleep waits for d, returning early with the context's error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,255 @@
+package golph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy retries quickly so the tests don't sleep.
+func testRetryPolicy(events *[]*RetryEvent) *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MinBackoff = time.Millisecond
+	policy.MaxBackoff = 2 * time.Millisecond
+	policy.RetryCodes = []string{"ERR-CONDUIT-CORE"}
+	policy.OnRetry = func(event *RetryEvent) {
+		*events = append(*events, event)
+	}
+	return policy
+}
+
+func TestRetry_TransientStatus(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var events []*RetryEvent
+	client.RetryPolicy = testRetryPolicy(&events)
+
+	attempts := 0
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.PostFormValue("api.token") != "api token goes here" {
+			t.Errorf("Attempt %d lost the request body: %v", attempts, r.PostForm)
+		}
+		if attempts < 3 {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"result":{"phid":"PHID-USER-1","userName":"alice"},"error_code":null,"error_info":null}`)
+	})
+
+	completed := 0
+	client.OnRequestCompleted(func(*http.Request, *http.Response) {
+		completed++
+	})
+
+	user, _, err := client.Users.WhoAmI(ctx)
+	if err != nil {
+		t.Fatalf("Users.WhoAmI returned error: %v", err)
+	}
+
+	if user.Username != "alice" || attempts != 3 || completed != 3 {
+		t.Errorf("Users.WhoAmI returned %+v after %d attempts and %d callbacks, expected alice after 3", user, attempts, completed)
+	}
+
+	if len(events) != 2 || events[0].Attempt != 1 || events[1].Attempt != 2 || events[0].Method != "user.whoami" {
+		t.Errorf("OnRetry saw %+v, expected attempts 1 and 2 of user.whoami", events)
+	}
+	if events[0].Response == nil || events[0].Response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("OnRetry saw response %+v, expected a 503", events[0].Response)
+	}
+}
+
+func TestRetry_ConduitCode(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var events []*RetryEvent
+	client.RetryPolicy = testRetryPolicy(&events)
+
+	attempts := 0
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		fmt.Fprint(w, `{"result":null,"error_code":"ERR-CONDUIT-CORE","error_info":"Database is read only."}`)
+	})
+
+	_, _, err := client.Users.WhoAmI(ctx)
+	if _, ok := err.(*ConduitError); !ok {
+		t.Errorf("Users.WhoAmI returned %v, expected a *ConduitError", err)
+	}
+
+	if attempts != 4 || len(events) != 3 {
+		t.Errorf("Made %d attempts and %d retries, expected 4 and 3", attempts, len(events))
+	}
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var events []*RetryEvent
+	client.RetryPolicy = testRetryPolicy(&events)
+
+	attempts := 0
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		fmt.Fprint(w, `{"result":null,"error_code":"ERR-INVALID-AUTH","error_info":"Bad token."}`)
+	})
+
+	_, _, err := client.Users.WhoAmI(ctx)
+	if !IsInvalidAuth(err) || attempts != 1 || len(events) != 0 {
+		t.Errorf("Users.WhoAmI returned %v after %d attempts, expected one invalid auth attempt", err, attempts)
+	}
+}
+
+func TestRetry_NonIdempotentMethod(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var events []*RetryEvent
+	client.RetryPolicy = testRetryPolicy(&events)
+
+	attempts := 0
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	})
+
+	_, _, err := client.Tasks.Create(ctx, &TaskCreateRequest{Title: "Once only"})
+	if err == nil {
+		t.Errorf("Tasks.Create returned no error, expected a 502")
+	}
+
+	if attempts != 1 {
+		t.Errorf("maniphest.edit was sent %d times, expected once", attempts)
+	}
+}
+
+func TestRetry_ContextCancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client.RetryPolicy = DefaultRetryPolicy()
+	client.RetryPolicy.MinBackoff = time.Hour
+	client.RetryPolicy.OnRetry = func(*RetryEvent) {
+		cancel()
+	}
+
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	})
+
+	_, _, err := client.Users.WhoAmI(ctx)
+	if err != context.Canceled {
+		t.Errorf("Users.WhoAmI returned %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestRetry_BadCertificate(t *testing.T) {
+	var events []*RetryEvent
+
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	client := NewClient("api token goes here", tlsServer.URL, nil)
+	client.RetryPolicy = testRetryPolicy(&events)
+
+	_, _, err := client.Users.WhoAmI(ctx)
+	if _, ok := err.(*url.Error); !ok {
+		t.Errorf("Users.WhoAmI returned %v, expected a *url.Error", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Users.WhoAmI was retried %d times, expected no retries", len(events))
+	}
+}
+
+// netError is a net.Error for the retryable tests.
+type netError struct {
+	timeout   bool
+	temporary bool
+}
+
+func (e netError) Error() string   { return "network error" }
+func (e netError) Timeout() bool   { return e.timeout }
+func (e netError) Temporary() bool { return e.temporary }
+
+func TestRetryPolicy_retryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	req := httptest.NewRequest("POST", "/api/user.whoami", nil)
+
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{netError{timeout: true}, true},
+		{netError{temporary: true}, true},
+		{netError{}, false},
+		{errors.New("unsupported protocol scheme"), false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+	}
+
+	for _, c := range cases {
+		err := &url.Error{Op: "Post", URL: "https://phabricator.example.com/api/user.whoami", Err: c.err}
+		if retryable := policy.retryable(req, err); retryable != c.expected {
+			t.Errorf("retryable(%v) returned %v, expected %v", c.err, retryable, c.expected)
+		}
+	}
+}
+
+func TestIsIdempotentMethod(t *testing.T) {
+	cases := map[string]bool{
+		"maniphest.search":             true,
+		"maniphest.info":               true,
+		"differential.querydiffs":      true,
+		"differential.setdiffproperty": true,
+		"maniphest.createtask":         false,
+		"maniphest.update":             false,
+		"maniphest.edit":               false,
+		"differential.createrawdiff":   false,
+		"harbormaster.sendmessage":     false,
+	}
+
+	for method, expected := range cases {
+		if got := IsIdempotentMethod(method); got != expected {
+			t.Errorf("IsIdempotentMethod(%q) = %v, expected %v", method, got, expected)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	cases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 150 * time.Millisecond, 300 * time.Millisecond},
+		{10, 150 * time.Millisecond, 300 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		if wait := policy.backoff(c.attempt, nil); wait < c.min || wait > c.max {
+			t.Errorf("backoff(%d) = %v, expected between %v and %v", c.attempt, wait, c.min, c.max)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"2"}}}
+	if wait := policy.backoff(1, resp); wait != 2*time.Second {
+		t.Errorf("backoff with Retry-After: 2 = %v, expected 2s", wait)
+	}
+}
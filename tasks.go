@@ -1,28 +1,55 @@
 package golph
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const tasksQueryPath = "api/maniphest.query"
 const tasksFetchPath = "api/maniphest.info"
-const tasksCreatePath = "api/maniphest.createtask"
-const tasksUpdatePath = "api/maniphest.update"
+const tasksSearchPath = "api/maniphest.search"
+const tasksEditMethod = "maniphest.edit"
+const taskStatusesQueryPath = "api/maniphest.querystatuses"
 
 // TasksService is an interface for interfacing with tasks (Maniphest)
 // See: https://secure.phabricator.com/conduit/ (and search for maniphest)
+//
+// Find and Edit use maniphest.search and maniphest.edit, which can reach
+// everything a task has. Search, Create and Update keep the requests of the
+// deprecated methods they replace, as thin wrappers over Find and Edit. List
+// and Get stay on the deprecated maniphest.query and maniphest.info.
 type TasksService interface {
-	List(*ListOptions) ([]Task, *Response, error)
-	Search(*TaskSearchRequest) ([]Task, *Response, error)
-	Get(string) (*Task, *Response, error)
-	Create(*TaskCreateRequest) (*Task, *Response, error)
-	Update(*TaskUpdateRequest) (*Response, error)
-	Delete(string) (*Response, error)
+	List(context.Context, *ListOptions) ([]Task, *Response, error)
+	ListPager(context.Context, *ListOptions) *Pager
+	Search(context.Context, *TaskSearchRequest) ([]Task, *Response, error)
+	Get(context.Context, string) (*Task, *Response, error)
+	Create(context.Context, *TaskCreateRequest) (*Task, *Response, error)
+	Update(context.Context, *TaskUpdateRequest) (*Response, error)
+	Delete(context.Context, string, string) (*Response, error)
+	Find(context.Context, *TaskFindRequest) ([]Task, *Response, error)
+	FindPager(context.Context, *TaskFindRequest) *Pager
+	Edit(context.Context, *TaskEditRequest) (*EditResult, *Response, error)
+	Close(context.Context, string, string, string) (*EditResult, *Response, error)
+	QueryStatuses(context.Context) (*TaskStatuses, *Response, error)
+	Relatives(context.Context, *Task) (*Response, error)
+	At(context.Context, string, time.Time) (*Task, *Response, error)
 }
 
 // TasksServiceOp handles communication with the conduit methods
 type TasksServiceOp struct {
 	client *Client
+
+	// deleteStatus is the closed status Delete uses, once it is known.
+	deleteStatusMu sync.Mutex
+	deleteStatus   string
 }
 
 var _ TasksService = &TasksServiceOp{}
@@ -57,7 +84,8 @@ var _ TasksService = &TasksServiceOp{}
 }
 */
 type Task struct {
-	PHID          string   `json:"phid"`
+	ID            int      `json:"id,string"`
+	PHID          PHID     `json:"phid"`
 	Author        string   `json:"authorPHID"`
 	Owner         string   `json:"ownerPHID"`
 	Title         string   `json:"title"`
@@ -71,16 +99,83 @@ type Task struct {
 	IsClosed      bool     `json:"isClosed"`
 	Priority      string   `json:"priority"`
 	PriorityColor string   `json:"priorityColor"`
+
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+
+	// DependsOn lists the tasks this one depends on, which are what newer
+	// Phabricator calls its subtasks. Only filled in by the older methods.
+	DependsOn []string `json:"dependsOnTaskPHIDs"`
+
+	// CustomFields holds the values of custom fields, keyed the way
+	// SetCustomField takes them, such as "mycompany.estimate".
+	CustomFields TaskCustomFields `json:"auxiliary"`
+
+	// Only filled in by Find.
+	Subtype string                  `json:"subtype,omitempty"`
+	Columns map[string][]TaskColumn `json:"columns,omitempty"`
+	Points  *float64                `json:"points,omitempty"`
+
+	// Only filled in by Relatives.
+	Parents  []string `json:"parentPHIDs,omitempty"`
+	Subtasks []string `json:"subtaskPHIDs,omitempty"`
 }
 
 func (f Task) String() string {
 	return Stringify(f)
 }
 
+// customFieldPrefixes are stripped from the keys of custom fields:
+// maniphest.info uses the first, maniphest.search the second.
+var customFieldPrefixes = []string{"std:maniphest:", "custom."}
+
+// TaskCustomFields holds the custom field values of a task.
+type TaskCustomFields map[string]interface{}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *TaskCustomFields) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = TaskCustomFields{}
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*m = TaskCustomFields{}
+	for key, value := range fields {
+		(*m)[customFieldKey(key)] = value
+	}
+	return nil
+}
+
+func customFieldKey(key string) string {
+	for _, prefix := range customFieldPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix)
+		}
+	}
+	return key
+}
+
+// TaskColumn is a workboard column a task is in. Task.Columns lists them by
+// the PHID of the project whose board they are on.
+type TaskColumn struct {
+	ID   int    `json:"id"`
+	PHID string `json:"phid"`
+	Name string `json:"name"`
+}
+
 type TaskGetRequest struct {
 	TaskId string `form:"task_id"`
 }
 
+// TaskSearchRequest represents a search in the terms of the deprecated
+// maniphest.query. Search runs it through maniphest.search. IDs, PHIDs and
+// ProjectPHIDs may be given as a JSON list or separated by commas.
 type TaskSearchRequest struct {
 	IDs          string   `form:"ids"`
 	PHIDs        string   `form:"phids"`
@@ -94,7 +189,9 @@ type TaskSearchRequest struct {
 	Offset       string   `form:"offset"`
 }
 
-// TaskCreateRequest represents a request to create a Task.
+// TaskCreateRequest represents a request to create a Task, in the terms of the
+// deprecated maniphest.createtask. Projects and CCs may be given as a JSON list
+// or separated by commas.
 type TaskCreateRequest struct {
 	Title       string `form:"title"`
 	Description string `form:"description"`
@@ -104,7 +201,9 @@ type TaskCreateRequest struct {
 	Priority    string `form:"priority"`
 }
 
-// TaskUpdateRequest represents a request to create a Task.
+// TaskUpdateRequest represents a request to update a Task, in the terms of the
+// deprecated maniphest.update. Projects and CCPHIDs replace the task's projects
+// and subscribers.
 type TaskUpdateRequest struct {
 	Id          string `form:"id"`
 	PHID        string `form:"phid"`
@@ -117,73 +216,678 @@ type TaskUpdateRequest struct {
 	Comment     string `form:"comments"`
 }
 
+// TaskFindConstraints narrows down the results of maniphest.search.
+type TaskFindConstraints struct {
+	IDs           []int    `json:"ids,omitempty"`
+	PHIDs         []string `json:"phids,omitempty"`
+	AssignedPHIDs []string `json:"assigned,omitempty"`
+	AuthorPHIDs   []string `json:"authorPHIDs,omitempty"`
+	Statuses      []string `json:"statuses,omitempty"`
+	Priorities    []int    `json:"priorities,omitempty"`
+	Subtypes      []string `json:"subtypes,omitempty"`
+	ColumnPHIDs   []string `json:"columnPHIDs,omitempty"`
+	HasParents    *bool    `json:"hasParents,omitempty"`
+	HasSubtasks   *bool    `json:"hasSubtasks,omitempty"`
+	ParentIDs     []int    `json:"parentIDs,omitempty"`
+	SubtaskIDs    []int    `json:"subtaskIDs,omitempty"`
+	Projects      []string `json:"projects,omitempty"`
+	Subscribers   []string `json:"subscribers,omitempty"`
+	CreatedStart  int64    `json:"createdStart,omitempty"`
+	CreatedEnd    int64    `json:"createdEnd,omitempty"`
+	ModifiedStart int64    `json:"modifiedStart,omitempty"`
+	ModifiedEnd   int64    `json:"modifiedEnd,omitempty"`
+	Query         string   `json:"query,omitempty"`
+}
+
+// TaskFindAttachments asks maniphest.search for extra data.
+type TaskFindAttachments struct {
+	Columns     bool `json:"columns,omitempty"`
+	Projects    bool `json:"projects,omitempty"`
+	Subscribers bool `json:"subscribers,omitempty"`
+}
+
+// TaskFindRequest represents a request to maniphest.search.
+type TaskFindRequest struct {
+	QueryKey    string               `json:"queryKey,omitempty"`
+	Constraints *TaskFindConstraints `json:"constraints,omitempty"`
+	Attachments *TaskFindAttachments `json:"attachments,omitempty"`
+	Order       string               `json:"order,omitempty"`
+	Before      string               `json:"before,omitempty"`
+	After       string               `json:"after,omitempty"`
+	Limit       int                  `json:"limit,omitempty"`
+}
+
+// TaskEditRequest represents a request to maniphest.edit. Leave
+// ObjectIdentifier empty to create a new task.
+type TaskEditRequest EditRequest
+
+// SetOwner assigns a task to a user. An empty PHID unassigns it.
+func SetOwner(userPHID string) Transaction {
+	if userPHID == "" {
+		return Transaction{Type: "owner", Value: nil}
+	}
+	return Transaction{Type: "owner", Value: userPHID}
+}
+
+// SetPriority changes the priority of a task, given as its keyword such as
+// "high" or "unbreak".
+func SetPriority(priority string) Transaction {
+	return Transaction{Type: "priority", Value: priority}
+}
+
+// SetPoints sets the story points of a task.
+func SetPoints(points float64) Transaction {
+	return Transaction{Type: "points", Value: points}
+}
+
+// SetSubtype changes the subtype of a task.
+func SetSubtype(subtype string) Transaction {
+	return Transaction{Type: "subtype", Value: subtype}
+}
+
+// AddParents makes a task a subtask of the given tasks.
+func AddParents(taskPHIDs ...string) Transaction {
+	return Transaction{Type: "parents.add", Value: taskPHIDs}
+}
+
+// RemoveParents stops a task being a subtask of the given tasks.
+func RemoveParents(taskPHIDs ...string) Transaction {
+	return Transaction{Type: "parents.remove", Value: taskPHIDs}
+}
+
+// AddSubtasks makes the given tasks subtasks of a task.
+func AddSubtasks(taskPHIDs ...string) Transaction {
+	return Transaction{Type: "subtasks.add", Value: taskPHIDs}
+}
+
+// RemoveSubtasks stops the given tasks being subtasks of a task.
+func RemoveSubtasks(taskPHIDs ...string) Transaction {
+	return Transaction{Type: "subtasks.remove", Value: taskPHIDs}
+}
+
+// SetCustomField sets a custom field, given by its key without the "custom."
+// prefix, such as "mycompany.estimate".
+func SetCustomField(key string, value interface{}) Transaction {
+	return Transaction{Type: "custom." + key, Value: value}
+}
+
+// legacyList reads a list given the way the deprecated maniphest methods took
+// them, as a JSON list like ["PHID-PROJ-1"] or separated by commas.
+func legacyList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(strings.Trim(strings.TrimSpace(s), "[]"), ",") {
+		item = strings.Trim(strings.TrimSpace(item), `"`)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// legacyStatuses are the maniphest.query statuses that don't map to a single
+// maniphest.search status.
+var legacyStatuses = map[string][]string{
+	"":              nil,
+	"status-any":    nil,
+	"status-open":   {"open()"},
+	"status-closed": {"closed()"},
+}
+
+// legacyOrders maps maniphest.query orders to maniphest.search orders.
+var legacyOrders = map[string]string{
+	"order-priority": "priority",
+	"order-created":  "newest",
+	"order-modified": "updated",
+	"order-title":    "title",
+}
+
+// legacyPriorities maps the default priorities, by name or value, to the
+// keywords maniphest.edit takes.
+var legacyPriorities = map[string]string{
+	"Unbreak Now!": "unbreak",
+	"Needs Triage": "triage",
+	"High":         "high",
+	"Normal":       "normal",
+	"Low":          "low",
+	"Wishlist":     "wish",
+	"100":          "unbreak",
+	"90":           "triage",
+	"80":           "high",
+	"50":           "normal",
+	"25":           "low",
+	"0":            "wish",
+}
+
+func legacyPriority(priority string) string {
+	if keyword, ok := legacyPriorities[priority]; ok {
+		return keyword
+	}
+	return priority
+}
+
+func legacyNumber(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// toFind returns the maniphest.search request for a search, along with the
+// number of results to skip and to return, since maniphest.search pages by
+// cursor rather than offset.
+func (r *TaskSearchRequest) toFind() (findRequest *TaskFindRequest, offset int, limit int, err error) {
+	constraints := &TaskFindConstraints{
+		PHIDs:         legacyList(r.PHIDs),
+		AssignedPHIDs: r.OwnerPHIDs,
+		AuthorPHIDs:   r.AuthorPHIDs,
+		Projects:      r.ProjectPHIDs,
+		Query:         r.FullText,
+	}
+
+	for _, id := range legacyList(r.IDs) {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid task ID %q", id)
+		}
+		constraints.IDs = append(constraints.IDs, n)
+	}
+
+	if statuses, ok := legacyStatuses[r.Status]; ok {
+		constraints.Statuses = statuses
+	} else {
+		constraints.Statuses = []string{strings.TrimPrefix(r.Status, "status-")}
+	}
+
+	order, ok := legacyOrders[r.Order]
+	if !ok {
+		order = r.Order
+	}
+
+	if offset, err = legacyNumber(r.Offset); err != nil {
+		return nil, 0, 0, err
+	}
+	if limit, err = legacyNumber(r.Limit); err != nil {
+		return nil, 0, 0, err
+	}
+
+	findRequest = &TaskFindRequest{
+		Constraints: constraints,
+		Attachments: &TaskFindAttachments{Projects: true, Subscribers: true},
+		Order:       order,
+	}
+	if limit > 0 && offset+limit < 100 {
+		findRequest.Limit = offset + limit
+	}
+	return findRequest, offset, limit, nil
+}
+
+// transactions returns the maniphest.edit transactions that create the task.
+func (r *TaskCreateRequest) transactions() []Transaction {
+	var transactions []Transaction
+	if r.Title != "" {
+		transactions = append(transactions, SetTitle(r.Title))
+	}
+	if r.Description != "" {
+		transactions = append(transactions, SetDescription(r.Description))
+	}
+	if projects := legacyList(r.Projects); len(projects) > 0 {
+		transactions = append(transactions, AddProjects(projects...))
+	}
+	if r.OwnerPHID != "" {
+		transactions = append(transactions, SetOwner(r.OwnerPHID))
+	}
+	if ccs := legacyList(r.CCs); len(ccs) > 0 {
+		transactions = append(transactions, AddSubscribers(ccs...))
+	}
+	if r.Priority != "" {
+		transactions = append(transactions, SetPriority(legacyPriority(r.Priority)))
+	}
+	return transactions
+}
+
+// transactions returns the maniphest.edit transactions that update the task.
+func (r *TaskUpdateRequest) transactions() []Transaction {
+	var transactions []Transaction
+	if r.Title != "" {
+		transactions = append(transactions, SetTitle(r.Title))
+	}
+	if r.Description != "" {
+		transactions = append(transactions, SetDescription(r.Description))
+	}
+	if r.Projects != "" {
+		transactions = append(transactions, Transaction{Type: "projects.set", Value: legacyList(r.Projects)})
+	}
+	if r.OwnerPHIDs != "" {
+		transactions = append(transactions, SetOwner(r.OwnerPHIDs))
+	}
+	if r.CCPHIDs != "" {
+		transactions = append(transactions, Transaction{Type: "subscribers.set", Value: legacyList(r.CCPHIDs)})
+	}
+	if r.Priority != "" {
+		transactions = append(transactions, SetPriority(legacyPriority(r.Priority)))
+	}
+	if r.Comment != "" {
+		transactions = append(transactions, Comment(r.Comment))
+	}
+	return transactions
+}
+
+type taskSearchStatus struct {
+	Value string `json:"value"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type taskSearchPriority struct {
+	Value int    `json:"value"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// taskSearchPoints holds story points, which are sent as a number, a numeric
+// string or null.
+type taskSearchPoints struct {
+	Value *float64
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (p *taskSearchPoints) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		p.Value = nil
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		str = unquoted
+	}
+	if str == "" {
+		p.Value = nil
+		return nil
+	}
+
+	points, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return fmt.Errorf("invalid story points %s: %v", data, err)
+	}
+	p.Value = &points
+	return nil
+}
+
+type taskSearchFields struct {
+	Name        string `json:"name"`
+	Description struct {
+		Raw string `json:"raw"`
+	} `json:"description"`
+	AuthorPHID   string             `json:"authorPHID"`
+	OwnerPHID    string             `json:"ownerPHID"`
+	Status       taskSearchStatus   `json:"status"`
+	Priority     taskSearchPriority `json:"priority"`
+	Subtype      string             `json:"subtype"`
+	Points       taskSearchPoints   `json:"points"`
+	DateCreated  *Timestamp         `json:"dateCreated"`
+	DateModified *Timestamp         `json:"dateModified"`
+
+	// Custom fields are mixed in with the others, keyed "custom.<key>".
+	Custom TaskCustomFields `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, picking the custom
+// fields out from among the standard ones.
+func (f *taskSearchFields) UnmarshalJSON(data []byte) error {
+	type fields taskSearchFields
+	if err := json.Unmarshal(data, (*fields)(f)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	for key, value := range all {
+		if strings.HasPrefix(key, "custom.") {
+			if f.Custom == nil {
+				f.Custom = TaskCustomFields{}
+			}
+			f.Custom[customFieldKey(key)] = value
+		}
+	}
+	return nil
+}
+
+// taskSearchBoards holds the columns attachment, keyed by board project PHID.
+type taskSearchBoards map[string]struct {
+	Columns []TaskColumn `json:"columns"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *taskSearchBoards) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = taskSearchBoards{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]struct {
+		Columns []TaskColumn `json:"columns"`
+	})(m))
+}
+
+type taskSearchAttachments struct {
+	Columns struct {
+		Boards taskSearchBoards `json:"boards"`
+	} `json:"columns"`
+	Subscribers struct {
+		SubscriberPHIDs []string `json:"subscriberPHIDs"`
+	} `json:"subscribers"`
+	Projects struct {
+		ProjectPHIDs []string `json:"projectPHIDs"`
+	} `json:"projects"`
+}
+
+type taskSearchData struct {
+	ID          int                   `json:"id"`
+	PHID        PHID                  `json:"phid"`
+	Fields      taskSearchFields      `json:"fields"`
+	Attachments taskSearchAttachments `json:"attachments"`
+}
+
+// toTask converts a maniphest.search result to a Task, building its URI from
+// the base URL of the Phabricator install.
+func (d taskSearchData) toTask(baseURL *url.URL) Task {
+	task := Task{
+		ID:            d.ID,
+		PHID:          d.PHID,
+		Author:        d.Fields.AuthorPHID,
+		Owner:         d.Fields.OwnerPHID,
+		Title:         d.Fields.Name,
+		Description:   d.Fields.Description.Raw,
+		Projects:      d.Attachments.Projects.ProjectPHIDs,
+		CCs:           d.Attachments.Subscribers.SubscriberPHIDs,
+		Status:        d.Fields.Status.Value,
+		StatusName:    d.Fields.Status.Name,
+		Priority:      d.Fields.Priority.Name,
+		PriorityColor: d.Fields.Priority.Color,
+		ObjectName:    fmt.Sprintf("T%d", d.ID),
+		Subtype:       d.Fields.Subtype,
+		Points:        d.Fields.Points.Value,
+		DateCreated:   d.Fields.DateCreated,
+		DateModified:  d.Fields.DateModified,
+		CustomFields:  d.Fields.Custom,
+	}
+
+	if baseURL != nil {
+		task.URI = baseURL.ResolveReference(&url.URL{Path: task.ObjectName}).String()
+	}
+
+	if len(d.Attachments.Columns.Boards) > 0 {
+		task.Columns = map[string][]TaskColumn{}
+		for board, columns := range d.Attachments.Columns.Boards {
+			task.Columns[board] = columns.Columns
+		}
+	}
+
+	return task
+}
+
+type TaskSearchResult struct {
+	Data   []taskSearchData  `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
+}
+
+type TaskSearchResponse struct {
+	Result    TaskSearchResult `json:"result"`
+	ErrorCode string           `json:"error_code,omitempty"`
+	ErrorInfo string           `json:"error_info,omitempty"`
+}
+
+// TaskStatuses describes the task statuses configured on a Phabricator
+// install, as maniphest.querystatuses reports them.
+type TaskStatuses struct {
+	Default       string            `json:"defaultStatus"`
+	DefaultClosed string            `json:"defaultClosedStatus"`
+	Duplicate     string            `json:"duplicateStatus"`
+	Open          StatusList        `json:"openStatuses"`
+	Closed        StatusList        `json:"closedStatuses"`
+	All           StatusList        `json:"allStatuses"`
+	Names         map[string]string `json:"statusMap"`
+}
+
+// IsClosed reports whether status is one of the closed statuses.
+func (s *TaskStatuses) IsClosed(status string) bool {
+	for _, closed := range s.Closed {
+		if closed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusList is a list of task statuses, which PHP sometimes sends as a map keyed
+// by position.
+type StatusList []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *StatusList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+
+	var byPosition map[string]string
+	if err := json.Unmarshal(data, &byPosition); err != nil {
+		return err
+	}
+
+	positions := make([]int, 0, len(byPosition))
+	for k := range byPosition {
+		position, err := strconv.Atoi(k)
+		if err != nil {
+			return err
+		}
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+
+	*l = StatusList{}
+	for _, position := range positions {
+		*l = append(*l, byPosition[strconv.Itoa(position)])
+	}
+	return nil
+}
+
+type TaskStatusesResponse struct {
+	Result    TaskStatuses `json:"result"`
+	ErrorCode string       `json:"error_code,omitempty"`
+	ErrorInfo string       `json:"error_info,omitempty"`
+}
+
 type SingleTaskResponse struct {
 	Task      Task   `json:"result"`
 	ErrorCode string `json:"error_code,omitempty"`
 	ErrorInfo string `json:"error_info,omitempty"`
 }
 
-type TaskResponse struct {
-	Tasks     map[string]Task `json:"result"`
-	ErrorCode string          `json:"error_code,omitempty"`
-	ErrorInfo string          `json:"error_info,omitempty"`
+// TaskMap is a set of tasks keyed by PHID.
+type TaskMap map[string]Task
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting the empty
+// list PHP sends in place of an empty map.
+func (m *TaskMap) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(data) {
+		*m = TaskMap{}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]Task)(m))
+}
+
+// sorted returns the tasks ordered by ID, so that results don't change order
+// from one call to the next.
+func (m TaskMap) sorted() []Task {
+	var list []Task
+	for _, task := range m {
+		list = append(list, task)
+	}
+	sort.Sort(tasksByID(list))
+	return list
 }
 
-// Search for tasks
-func (f *TasksServiceOp) Search(searchRequest *TaskSearchRequest) ([]Task, *Response, error) {
-	path := tasksQueryPath
+type tasksByID []Task
 
-	req, err := f.client.NewRequest("POST", path, searchRequest)
+func (t tasksByID) Len() int      { return len(t) }
+func (t tasksByID) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t tasksByID) Less(i, j int) bool {
+	if t[i].ID != t[j].ID {
+		return t[i].ID < t[j].ID
+	}
+	return t[i].PHID < t[j].PHID
+}
+
+type TaskResponse struct {
+	Tasks     TaskMap `json:"result"`
+	ErrorCode string  `json:"error_code,omitempty"`
+	ErrorInfo string  `json:"error_info,omitempty"`
+}
+
+// Find searches for tasks (through maniphest.search).
+func (f *TasksServiceOp) Find(ctx context.Context, findRequest *TaskFindRequest) ([]Task, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", tasksSearchPath, findRequest)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	root := new(TaskResponse)
+	root := new(TaskSearchResponse)
 	resp, err := f.client.Do(req, root)
 	if err != nil {
 		return nil, resp, err
 	}
 
 	var list []Task
-	for _, task := range root.Tasks {
-		list = append(list, task)
+	for _, data := range root.Result.Data {
+		list = append(list, data.toTask(f.client.BaseURL))
 	}
 	return list, resp, err
 }
 
-// List all tasks.
-func (f *TasksServiceOp) List(opt *ListOptions) ([]Task, *Response, error) {
-	path := tasksQueryPath
-	path, err := addOptions(path, opt)
-	if err != nil {
-		return nil, nil, err
+// FindPager returns a Pager that follows the maniphest.search cursor through
+// every page of results. Items are Task values.
+func (f *TasksServiceOp) FindPager(ctx context.Context, findRequest *TaskFindRequest) *Pager {
+	page := TaskFindRequest{}
+	if findRequest != nil {
+		page = *findRequest
 	}
 
-	req, err := f.client.NewRequest("POST", path, nil)
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", tasksSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(TaskSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toTask(f.client.BaseURL))
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// Edit creates or changes a task by applying transactions to it (through
+// maniphest.edit).
+func (f *TasksServiceOp) Edit(ctx context.Context, editRequest *TaskEditRequest) (*EditResult, *Response, error) {
+	if editRequest == nil {
+		editRequest = &TaskEditRequest{}
+	}
+
+	return f.client.Edit(ctx, tasksEditMethod, editRequest.ObjectIdentifier, editRequest.Transactions)
+}
+
+// Search for tasks. This takes the terms of the deprecated maniphest.query, and
+// runs the search through maniphest.search (see Find).
+func (f *TasksServiceOp) Search(ctx context.Context, searchRequest *TaskSearchRequest) ([]Task, *Response, error) {
+	if searchRequest == nil {
+		searchRequest = &TaskSearchRequest{}
+	}
+
+	findRequest, offset, limit, err := searchRequest.toFind()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	root := new(TaskResponse)
-	resp, err := f.client.Do(req, root)
-	if err != nil {
-		return nil, resp, err
+	pager := f.FindPager(ctx, findRequest)
+	if limit > 0 {
+		pager.MaxResults = offset + limit
 	}
 
 	var list []Task
-	for _, task := range root.Tasks {
-		list = append(list, task)
+	for i := 0; pager.Next(); i++ {
+		if i >= offset {
+			list = append(list, pager.Item().(Task))
+		}
 	}
-	return list, resp, err
+	return list, pager.Response(), pager.Err()
+}
+
+// List tasks, paging through maniphest.query by offset until the Limit or
+// MaxResults of opt is reached, or every task has been read.
+func (f *TasksServiceOp) List(ctx context.Context, opt *ListOptions) ([]Task, *Response, error) {
+	pager := f.ListPager(ctx, opt)
+
+	var list []Task
+	for pager.Next() {
+		list = append(list, pager.Item().(Task))
+	}
+	return list, pager.Response(), pager.Err()
+}
+
+// ListPager returns a Pager over all tasks. Items are Task values.
+func (f *TasksServiceOp) ListPager(ctx context.Context, opt *ListOptions) *Pager {
+	return newPagerFor(ctx, opt, offsetPageFunc(opt, func(ctx context.Context, page *ListOptions) ([]interface{}, *Response, error) {
+		path := tasksQueryPath
+		path, err := addOptions(path, page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req, err := f.client.NewRequest(ctx, "POST", path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		root := new(TaskResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		var items []interface{}
+		for _, task := range root.Tasks.sorted() {
+			items = append(items, task)
+		}
+		return items, resp, err
+	}))
 }
 
-// Get an individual task (through the tasksFetchPath, maniphest.info).
-func (f *TasksServiceOp) Get(task_id string) (*Task, *Response, error) {
+// Get an individual task (through the tasksFetchPath, the deprecated maniphest.info).
+func (f *TasksServiceOp) Get(ctx context.Context, task_id string) (*Task, *Response, error) {
 	searchRequest := &TaskGetRequest{
 		TaskId: task_id, // This is the "5000" part of "T5000"
 	}
 
-	req, err := f.client.NewRequest("POST", tasksFetchPath, searchRequest)
+	req, err := f.client.NewRequest(ctx, "POST", tasksFetchPath, searchRequest)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -194,50 +898,188 @@ func (f *TasksServiceOp) Get(task_id string) (*Task, *Response, error) {
 		return nil, resp, err
 	}
 
-	if root.ErrorCode != "" {
-		return nil, resp, errors.New(root.ErrorInfo)
+	return &root.Task, resp, err
+}
+
+// Create a task. This takes the terms of the deprecated maniphest.createtask,
+// and creates the task through maniphest.edit (see Edit).
+func (f *TasksServiceOp) Create(ctx context.Context, createRequest *TaskCreateRequest) (*Task, *Response, error) {
+	if createRequest == nil {
+		createRequest = &TaskCreateRequest{}
 	}
 
-	return &root.Task, resp, err
+	result, resp, err := f.Edit(ctx, &TaskEditRequest{Transactions: createRequest.transactions()})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return f.findByPHID(ctx, result.Object.PHID)
+}
+
+// Update a task. This takes the terms of the deprecated maniphest.update, and
+// updates the task through maniphest.edit (see Edit).
+func (f *TasksServiceOp) Update(ctx context.Context, updateRequest *TaskUpdateRequest) (*Response, error) {
+	if updateRequest == nil || (updateRequest.PHID == "" && updateRequest.Id == "") {
+		return nil, errors.New("Update needs the ID or PHID of the task")
+	}
+
+	task := updateRequest.PHID
+	if task == "" {
+		task = updateRequest.Id
+	}
+
+	_, resp, err := f.Edit(ctx, &TaskEditRequest{ObjectIdentifier: task, Transactions: updateRequest.transactions()})
+	return resp, err
+}
+
+// findByPHID returns a task with its columns, projects and subscribers.
+func (f *TasksServiceOp) findByPHID(ctx context.Context, taskPHID string) (*Task, *Response, error) {
+	tasks, resp, err := f.Find(ctx, &TaskFindRequest{
+		Constraints: &TaskFindConstraints{PHIDs: []string{taskPHID}},
+		Attachments: &TaskFindAttachments{Columns: true, Projects: true, Subscribers: true},
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(tasks) < 1 {
+		return nil, resp, errors.New("task " + taskPHID + " was not found")
+	}
+	return &tasks[0], resp, nil
 }
 
-// Create a task
-func (f *TasksServiceOp) Create(createRequest *TaskCreateRequest) (*Task, *Response, error) {
-	path := tasksCreatePath
+// Delete a Task. Phabricator can't delete tasks through Conduit, so this
+// closes it as invalid, or as won't fix where there is no invalid status,
+// leaving reason as a comment if it isn't empty. The status is looked up once,
+// on the first call.
+func (f *TasksServiceOp) Delete(ctx context.Context, task string, reason string) (*Response, error) {
+	status, resp, err := f.findDeleteStatus(ctx)
+	if err != nil {
+		return resp, err
+	}
 
-	req, err := f.client.NewRequest("POST", path, createRequest)
+	_, resp, err = f.Close(ctx, task, status, reason)
+	return resp, err
+}
+
+// findDeleteStatus returns the closed status Delete closes tasks with.
+func (f *TasksServiceOp) findDeleteStatus(ctx context.Context) (string, *Response, error) {
+	f.deleteStatusMu.Lock()
+	defer f.deleteStatusMu.Unlock()
+
+	if f.deleteStatus != "" {
+		return f.deleteStatus, nil, nil
+	}
+
+	statuses, resp, err := f.QueryStatuses(ctx)
+	if err != nil {
+		return "", resp, err
+	}
+
+	status := statuses.DefaultClosed
+	for _, preferred := range []string{"invalid", "wontfix"} {
+		if statuses.IsClosed(preferred) {
+			status = preferred
+			break
+		}
+	}
+
+	if status == "" {
+		return "", resp, errors.New("Phabricator has no closed task status")
+	}
+
+	f.deleteStatus = status
+	return status, resp, nil
+}
+
+// Close a task with the given closed status, such as "resolved", leaving
+// comment on it if it isn't empty. The task is identified by its ID, PHID or
+// monogram. QueryStatuses lists the statuses that close a task.
+func (f *TasksServiceOp) Close(ctx context.Context, task string, status string, comment string) (*EditResult, *Response, error) {
+	transactions := []Transaction{SetStatus(status)}
+	if comment != "" {
+		transactions = append(transactions, Comment(comment))
+	}
+
+	return f.client.Edit(ctx, tasksEditMethod, task, transactions)
+}
+
+// QueryStatuses returns the task statuses configured on the Phabricator
+// install (through maniphest.querystatuses).
+func (f *TasksServiceOp) QueryStatuses(ctx context.Context) (*TaskStatuses, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", taskStatusesQueryPath, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	root := new(SingleTaskResponse)
+	root := new(TaskStatusesResponse)
 	resp, err := f.client.Do(req, root)
 	if err != nil {
 		return nil, resp, err
 	}
 
-	return &root.Task, resp, err
+	return &root.Result, resp, err
 }
 
-// Update a Task
-func (f *TasksServiceOp) Update(updateRequest *TaskUpdateRequest) (*Response, error) {
-	path := tasksUpdatePath
+// Relatives fills in the Parents and Subtasks of a task found through Find or
+// Get (through maniphest.search).
+func (f *TasksServiceOp) Relatives(ctx context.Context, task *Task) (*Response, error) {
+	if task == nil || task.ID == 0 {
+		return nil, errors.New("a task ID is required to find its relatives")
+	}
 
-	req, err := f.client.NewRequest("POST", path, updateRequest)
+	parents, resp, err := f.relatedPHIDs(ctx, &TaskFindConstraints{SubtaskIDs: []int{task.ID}})
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
 
-	root := new(SingleTaskResponse)
-	resp, err := f.client.Do(req, root)
+	subtasks, resp, err := f.relatedPHIDs(ctx, &TaskFindConstraints{ParentIDs: []int{task.ID}})
 	if err != nil {
 		return resp, err
 	}
 
-	return resp, err
+	task.Parents = parents
+	task.Subtasks = subtasks
+	return resp, nil
 }
 
-// Delete a Task (Not Available Yet!)
-func (f *TasksServiceOp) Delete(task_id string) (*Response, error) {
-	return nil, errors.New("Delete is not available for Tasks")
+// relatedPHIDs returns the PHIDs of every task matching constraints.
+func (f *TasksServiceOp) relatedPHIDs(ctx context.Context, constraints *TaskFindConstraints) ([]string, *Response, error) {
+	pager := f.FindPager(ctx, &TaskFindRequest{Constraints: constraints})
+
+	phids := []string{}
+	for pager.Next() {
+		phids = append(phids, pager.Item().(Task).PHID.String())
+	}
+	return phids, pager.Response(), pager.Err()
+}
+
+// At returns a task as it was at the given time, rewinding it through its
+// transactions (see RewindTask). It returns nil if the task didn't exist yet.
+func (f *TasksServiceOp) At(ctx context.Context, taskPHID string, at time.Time) (*Task, *Response, error) {
+	task, resp, err := f.findByPHID(ctx, taskPHID)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	pager := f.client.Transactions.SearchPager(ctx, &TransactionSearchRequest{ObjectIdentifier: taskPHID})
+
+	var transactions []AppliedTransaction
+	for pager.Next() {
+		transaction := pager.Item().(AppliedTransaction)
+		transactions = append(transactions, transaction)
+
+		// Transactions come newest first, so the rest are all older.
+		if transaction.DateCreated != nil && !transaction.DateCreated.After(at) {
+			break
+		}
+	}
+
+	if pager.Response() != nil {
+		resp = pager.Response()
+	}
+	if err := pager.Err(); err != nil {
+		return nil, resp, err
+	}
+
+	return RewindTask(*task, transactions, at), resp, nil
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 const (
@@ -22,13 +23,14 @@ func TestTasks_ListTasks(t *testing.T) {
 		fmt.Fprint(w, listTaskJSON)
 	})
 
-	tasks, _, err := client.Tasks.List(nil)
+	tasks, _, err := client.Tasks.List(ctx, nil)
 	if err != nil {
 		t.Errorf("Tasks.List returned error: %v", err)
 	}
 
 	expected := []Task{
 		{
+			ID:            1000,
 			PHID:          "PHID-TASK-1",
 			Author:        "PHID-USER-1",
 			Owner:         "",
@@ -43,6 +45,10 @@ func TestTasks_ListTasks(t *testing.T) {
 			PriorityColor: "violet",
 			URI:           "https://phabricator.example.com/T1000",
 			ObjectName:    "T1000",
+			DateCreated:   &Timestamp{time.Unix(1415646583, 0)},
+			DateModified:  &Timestamp{time.Unix(1451336014, 0)},
+			DependsOn:     []string{},
+			CustomFields:  TaskCustomFields{},
 		},
 	}
 
@@ -51,6 +57,31 @@ func TestTasks_ListTasks(t *testing.T) {
 	}
 }
 
+func TestTasks_ListPagerOrder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.query", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"result":{"PHID-TASK-c":{"id":"12","phid":"PHID-TASK-c","title":"Twelve"},"PHID-TASK-a":{"id":"3","phid":"PHID-TASK-a","title":"Three"},"PHID-TASK-b":{"id":"7","phid":"PHID-TASK-b","title":"Seven"}},"error_code":null,"error_info":null}`)
+	})
+
+	for i := 0; i < 5; i++ {
+		var titles []string
+		pager := client.Tasks.ListPager(ctx, nil)
+		for pager.Next() {
+			titles = append(titles, pager.Item().(Task).Title)
+		}
+		if err := pager.Err(); err != nil {
+			t.Fatalf("Tasks.ListPager returned error: %v", err)
+		}
+
+		if expected := []string{"Three", "Seven", "Twelve"}; !reflect.DeepEqual(titles, expected) {
+			t.Fatalf("Tasks.ListPager returned %v, expected tasks in ID order %v", titles, expected)
+		}
+	}
+}
+
 func TestTasks_Get(t *testing.T) {
 	setup()
 	defer teardown()
@@ -61,12 +92,13 @@ func TestTasks_Get(t *testing.T) {
 		fmt.Fprint(w, getTaskJSON)
 	})
 
-	task, _, err := client.Tasks.Get("2000")
+	task, _, err := client.Tasks.Get(ctx, "2000")
 	if err != nil {
 		t.Errorf("Tasks.Get returned error: %v", err)
 	}
 
 	expected := &Task{
+		ID:            1000,
 		PHID:          "PHID-TASK-1",
 		Author:        "PHID-USER-1",
 		Owner:         "",
@@ -81,6 +113,10 @@ func TestTasks_Get(t *testing.T) {
 		PriorityColor: "violet",
 		URI:           "https://phabricator.example.com/T1000",
 		ObjectName:    "T1000",
+		DateCreated:   &Timestamp{time.Unix(1415646583, 0)},
+		DateModified:  &Timestamp{time.Unix(1451336014, 0)},
+		DependsOn:     []string{},
+		CustomFields:  TaskCustomFields{},
 	}
 
 	if !reflect.DeepEqual(task, expected) {
@@ -88,51 +124,85 @@ func TestTasks_Get(t *testing.T) {
 	}
 }
 
+func TestTasks_GetNotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.info", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":null,"error_code":"ERR_BAD_TASK","error_info":"No such Maniphest task exists."}`)
+	})
+
+	task, _, err := client.Tasks.Get(ctx, "404")
+	if !IsNotFound(err) {
+		t.Errorf("Tasks.Get returned error %v, expected a not found error", err)
+	}
+	if task != nil {
+		t.Errorf("Tasks.Get returned %+v, expected nil", task)
+	}
+}
+
+func TestTasks_Search(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"ids":[1000,1001],"assigned":["PHID-USER-1"],"projects":["PHID-PROJ-1"],"statuses":["open()"],"query":"golph"},"attachments":{"projects":true,"subscribers":true},"order":"newest","limit":3}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":1000,"phid":"PHID-TASK-1","fields":{"name":"Skipped"}},{"id":1001,"phid":"PHID-TASK-2","fields":{"name":"Found"}}],"cursor":{"limit":3,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	tasks, _, err := client.Tasks.Search(ctx, &TaskSearchRequest{
+		IDs:          "1000,1001",
+		OwnerPHIDs:   []string{"PHID-USER-1"},
+		ProjectPHIDs: []string{"PHID-PROJ-1"},
+		FullText:     "golph",
+		Status:       "status-open",
+		Order:        "order-created",
+		Offset:       "1",
+		Limit:        "2",
+	})
+	if err != nil {
+		t.Fatalf("Tasks.Search returned error: %v", err)
+	}
+
+	if len(tasks) != 1 || tasks[0].Title != "Found" {
+		t.Errorf("Tasks.Search returned %+v, expected only the second task", tasks)
+	}
+}
+
 func TestTasks_Create(t *testing.T) {
 	setup()
 	defer teardown()
 
 	createRequest := &TaskCreateRequest{
-		Title:       "Test List",
-		Description: "We only test one item in the list, because Phab returns a map and the order isn't fixed :(",
+		Title:       "Testing Golph",
+		Description: "Golph created this task",
 		Projects:    `["PHID-PROJ-1"]`,
 		OwnerPHID:   "PHID-USER-1",
 		CCs:         `["PHID-USER-2"]`,
 		Priority:    "Needs Triage",
 	}
 
-	mux.HandleFunc("/api/maniphest.createtask", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
-		if r.PostFormValue("title") != createRequest.Title {
-			t.Errorf("Form name = %+v, expected %+v", r.PostFormValue("title"), createRequest.Title)
-		}
-		fmt.Fprint(w, `{"result":{"id":"2000","phid":"PHID-TASK-2","authorPHID":"PHID-USER-1","ownerPHID":null,"ccPHIDs":["PHID-USER-2"],"status":"open","statusName":"Open","isClosed":false,"priority":"Normal","priorityColor":"green","title":"Testing Golph","description":"Golph created this task","projectPHIDs":["PHID-PROJ-1"],"uri":"https://phabricator.example.com/T2000","auxiliary":{},"objectName":"T2000","dateCreated":"1451337180","dateModified":"1451337180","dependsOnTaskPHIDs":[]},"error_code":null,"error_info":null}`)
+		testJSONParams(t, r, `{"transactions":[{"type":"title","value":"Testing Golph"},{"type":"description","value":"Golph created this task"},{"type":"projects.add","value":["PHID-PROJ-1"]},{"type":"owner","value":"PHID-USER-1"},{"type":"subscribers.add","value":["PHID-USER-2"]},{"type":"priority","value":"triage"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":2000,"phid":"PHID-TASK-2"},"transactions":[{"phid":"PHID-XACT-TASK-1"}]},"error_code":null,"error_info":null}`)
 	})
 
-	task, _, err := client.Tasks.Create(createRequest)
-	if err != nil {
-		t.Errorf("Task.Create returned error: %v", err)
-	}
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"phids":["PHID-TASK-2"]},"attachments":{"columns":true,"projects":true,"subscribers":true}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":2000,"type":"TASK","phid":"PHID-TASK-2","fields":{"name":"Testing Golph","description":{"raw":"Golph created this task"},"authorPHID":"PHID-USER-1","ownerPHID":"PHID-USER-1","status":{"value":"open","name":"Open","color":null},"priority":{"value":90,"subpriority":0,"name":"Needs Triage","color":"violet"},"points":null,"dateCreated":1451337180,"dateModified":1451337180},"attachments":{"columns":{"boards":[]},"projects":{"projectPHIDs":["PHID-PROJ-1"]},"subscribers":{"subscriberPHIDs":["PHID-USER-2"]}}}],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
 
-	expected := &Task{
-		PHID:          "PHID-TASK-2",
-		Author:        "PHID-USER-1",
-		Owner:         "",
-		Title:         "Testing Golph",
-		Description:   "Golph created this task",
-		Projects:      []string{"PHID-PROJ-1"},
-		CCs:           []string{"PHID-USER-2"},
-		Status:        "open",
-		StatusName:    "Open",
-		IsClosed:      false,
-		Priority:      "Normal",
-		PriorityColor: "green",
-		URI:           "https://phabricator.example.com/T2000",
-		ObjectName:    "T2000",
+	task, _, err := client.Tasks.Create(ctx, createRequest)
+	if err != nil {
+		t.Fatalf("Task.Create returned error: %v", err)
 	}
 
-	if !reflect.DeepEqual(task, expected) {
-		t.Errorf("Tasks.Create returned:\n%+v\nExpected:\n%+v", task, expected)
+	if task.ID != 2000 || task.PHID != "PHID-TASK-2" || task.Owner != "PHID-USER-1" || task.Priority != "Needs Triage" ||
+		!reflect.DeepEqual(task.Projects, []string{"PHID-PROJ-1"}) || !reflect.DeepEqual(task.CCs, []string{"PHID-USER-2"}) {
+		t.Errorf("Tasks.Create returned %+v", task)
 	}
 }
 
@@ -141,26 +211,304 @@ func TestTasks_Update(t *testing.T) {
 	defer teardown()
 
 	updateRequest := &TaskUpdateRequest{
-		Id:      "1000",
-		Title:   "Would update Title",
-		Comment: "This should add a comment",
+		Id:       "1000",
+		Title:    "Would update Title",
+		Projects: "PHID-PROJ-1, PHID-PROJ-2",
+		Priority: "80",
+		Comment:  "This should add a comment",
+	}
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"1000","transactions":[{"type":"title","value":"Would update Title"},{"type":"projects.set","value":["PHID-PROJ-1","PHID-PROJ-2"]},{"type":"priority","value":"high"},{"type":"comment","value":"This should add a comment"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":1000,"phid":"PHID-TASK-1"},"transactions":[{"phid":"PHID-XACT-TASK-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	_, err := client.Tasks.Update(ctx, updateRequest)
+	if err != nil {
+		t.Errorf("Tasks.Update returned error: %v", err)
+	}
+
+	if _, err := client.Tasks.Update(ctx, &TaskUpdateRequest{Title: "Which task?"}); err == nil {
+		t.Errorf("Tasks.Update without an ID or PHID returned no error")
 	}
+}
+
+func TestTasks_Find(t *testing.T) {
+	setup()
+	defer teardown()
 
-	mux.HandleFunc("/api/maniphest.update", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
-		if r.PostFormValue("id") != updateRequest.Id {
-			t.Errorf("Form name = %+v, expected %+v", r.PostFormValue("id"), updateRequest.Id)
+		testJSONParams(t, r, `{"constraints":{"statuses":["open"],"projects":["PHID-PROJ-1"],"hasParents":false},"attachments":{"columns":true,"projects":true,"subscribers":true},"order":"priority"}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":1000,"type":"TASK","phid":"PHID-TASK-1","fields":{"name":"Find tasks","description":{"raw":"Use maniphest.search"},"authorPHID":"PHID-USER-1","ownerPHID":null,"status":{"value":"open","name":"Open","color":null},"priority":{"value":90,"subpriority":0,"name":"Needs Triage","color":"violet"},"points":null,"subtype":"default","closerPHID":null,"dateClosed":null,"spacePHID":null,"dateCreated":1415646583,"dateModified":1451336014,"custom.mycompany.estimate":"3 days","policy":{"view":"users","interact":"users","edit":"users"}},"attachments":{"columns":{"boards":{"PHID-PROJ-1":{"columns":[{"id":5,"phid":"PHID-PCOL-1","name":"Backlog"}]}}},"projects":{"projectPHIDs":["PHID-PROJ-1"]},"subscribers":{"subscriberPHIDs":["PHID-USER-2"],"subscriberCount":1,"viewerIsSubscribed":false}}},{"id":1001,"type":"TASK","phid":"PHID-TASK-2","fields":{"name":"Unboarded","description":{"raw":""},"authorPHID":"PHID-USER-1","ownerPHID":"PHID-USER-2","status":{"value":"open","name":"Open","color":null},"priority":{"value":80,"subpriority":0,"name":"High","color":"red"},"points":"2.5","subtype":"bug"},"attachments":{"columns":{"boards":[]},"projects":{"projectPHIDs":[]},"subscribers":{"subscriberPHIDs":[]}}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`)
+	})
+
+	tasks, _, err := client.Tasks.Find(ctx, &TaskFindRequest{
+		Constraints: &TaskFindConstraints{Statuses: []string{"open"}, Projects: []string{"PHID-PROJ-1"}, HasParents: Bool(false)},
+		Attachments: &TaskFindAttachments{Columns: true, Projects: true, Subscribers: true},
+		Order:       "priority",
+	})
+	if err != nil {
+		t.Fatalf("Tasks.Find returned error: %v", err)
+	}
+
+	expected := []Task{
+		{
+			ID:            1000,
+			PHID:          "PHID-TASK-1",
+			Author:        "PHID-USER-1",
+			Title:         "Find tasks",
+			Description:   "Use maniphest.search",
+			Projects:      []string{"PHID-PROJ-1"},
+			CCs:           []string{"PHID-USER-2"},
+			Status:        "open",
+			StatusName:    "Open",
+			Priority:      "Needs Triage",
+			PriorityColor: "violet",
+			URI:           server.URL + "/T1000",
+			ObjectName:    "T1000",
+			Subtype:       "default",
+			Columns:       map[string][]TaskColumn{"PHID-PROJ-1": {{ID: 5, PHID: "PHID-PCOL-1", Name: "Backlog"}}},
+			DateCreated:   &Timestamp{time.Unix(1415646583, 0)},
+			DateModified:  &Timestamp{time.Unix(1451336014, 0)},
+			CustomFields:  TaskCustomFields{"mycompany.estimate": "3 days"},
+		},
+		{
+			ID:            1001,
+			PHID:          "PHID-TASK-2",
+			Author:        "PHID-USER-1",
+			Owner:         "PHID-USER-2",
+			Title:         "Unboarded",
+			Projects:      []string{},
+			CCs:           []string{},
+			Status:        "open",
+			StatusName:    "Open",
+			Priority:      "High",
+			PriorityColor: "red",
+			URI:           server.URL + "/T1001",
+			ObjectName:    "T1001",
+			Subtype:       "bug",
+			Points:        Float64(2.5),
+		},
+	}
+
+	if !reflect.DeepEqual(tasks, expected) {
+		t.Errorf("Tasks.Find returned:\n%+v\nExpected:\n%+v", tasks, expected)
+	}
+}
+
+func TestTasks_FindPager(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		after, _ := conduitParams(t, r)["after"].(string)
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"result":{"data":[{"id":1,"phid":"PHID-TASK-1","fields":{"name":"One"}}],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
+		case "1":
+			fmt.Fprint(w, `{"result":{"data":[{"id":2,"phid":"PHID-TASK-2","fields":{"name":"Two"}}],"cursor":{"limit":1,"after":null,"before":"2"}},"error_code":null,"error_info":null}`)
+		default:
+			t.Errorf("Unexpected cursor %q", after)
 		}
-		if r.PostFormValue("comments") != updateRequest.Comment {
-			t.Errorf("Form name = %+v, expected %+v", r.PostFormValue("comments"), updateRequest.Comment)
+	})
+
+	var titles []string
+	err := client.Tasks.FindPager(ctx, &TaskFindRequest{Limit: 1}).Each(func(item interface{}) error {
+		titles = append(titles, item.(Task).Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Tasks.FindPager returned error: %v", err)
+	}
+
+	if expected := []string{"One", "Two"}; !reflect.DeepEqual(titles, expected) {
+		t.Errorf("Tasks.FindPager returned %v, expected %v", titles, expected)
+	}
+}
+
+func TestTasks_Edit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"T1000","transactions":[{"type":"owner","value":null},{"type":"priority","value":"high"},{"type":"points","value":2.5},{"type":"subtype","value":"bug"},{"type":"parents.add","value":["PHID-TASK-2"]},{"type":"subtasks.remove","value":["PHID-TASK-3"]},{"type":"column","value":["PHID-PCOL-1"]},{"type":"custom.acme.estimate","value":"3d"}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":1000,"phid":"PHID-TASK-1"},"transactions":[{"phid":"PHID-XACT-TASK-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	result, _, err := client.Tasks.Edit(ctx, &TaskEditRequest{
+		ObjectIdentifier: "T1000",
+		Transactions: []Transaction{
+			SetOwner(""),
+			SetPriority("high"),
+			SetPoints(2.5),
+			SetSubtype("bug"),
+			AddParents("PHID-TASK-2"),
+			RemoveSubtasks("PHID-TASK-3"),
+			MoveToColumn("PHID-PCOL-1"),
+			SetCustomField("acme.estimate", "3d"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Tasks.Edit returned error: %v", err)
+	}
+
+	expected := &EditResult{
+		Object:       EditObject{ID: 1000, PHID: "PHID-TASK-1"},
+		Transactions: []EditTransaction{{PHID: "PHID-XACT-TASK-1"}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Tasks.Edit returned %+v, expected %+v", result, expected)
+	}
+}
+
+const taskStatusesJSON = `{"result":{"defaultStatus":"open","defaultClosedStatus":"resolved","duplicateStatus":"duplicate","openStatuses":["open"],"closedStatuses":{"1":"resolved","2":"wontfix","3":"invalid","4":"duplicate"},"allStatuses":["open","resolved","wontfix","invalid","duplicate"],"statusMap":{"open":"Open","resolved":"Resolved","wontfix":"Wontfix","invalid":"Invalid","duplicate":"Duplicate"}},"error_code":null,"error_info":null}`
+
+func TestTasks_QueryStatuses(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.querystatuses", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, taskStatusesJSON)
+	})
+
+	statuses, _, err := client.Tasks.QueryStatuses(ctx)
+	if err != nil {
+		t.Fatalf("Tasks.QueryStatuses returned error: %v", err)
+	}
+
+	expected := &TaskStatuses{
+		Default:       "open",
+		DefaultClosed: "resolved",
+		Duplicate:     "duplicate",
+		Open:          StatusList{"open"},
+		Closed:        StatusList{"resolved", "wontfix", "invalid", "duplicate"},
+		All:           StatusList{"open", "resolved", "wontfix", "invalid", "duplicate"},
+		Names:         map[string]string{"open": "Open", "resolved": "Resolved", "wontfix": "Wontfix", "invalid": "Invalid", "duplicate": "Duplicate"},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Tasks.QueryStatuses returned %+v, expected %+v", statuses, expected)
+	}
+
+	if statuses.IsClosed("open") || !statuses.IsClosed("wontfix") {
+		t.Errorf("TaskStatuses.IsClosed got open or wontfix wrong")
+	}
+}
+
+func TestTasks_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	queries := 0
+	mux.HandleFunc("/api/maniphest.querystatuses", func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		fmt.Fprint(w, taskStatusesJSON)
+	})
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if conduitParams(t, r)["objectIdentifier"] == "5000" {
+			testJSONParams(t, r, `{"objectIdentifier":"5000","transactions":[{"type":"status","value":"invalid"},{"type":"comment","value":"Filed by mistake."}]}`)
+		} else {
+			testJSONParams(t, r, `{"objectIdentifier":"5001","transactions":[{"type":"status","value":"invalid"}]}`)
 		}
-		fmt.Fprint(w, `{"result":{"id":"1000","phid":"PHID-TASK-2","authorPHID":"PHID-USER-1","ownerPHID":null,"ccPHIDs":["PHID-USER-2"],"status":"open","statusName":"Open","isClosed":false,"priority":"Normal","priorityColor":"green","title":"Would update Title","description":"Golph created this task","projectPHIDs":["PHID-PROJ-1"],"uri":"https://phabricator.example.com/T2000","auxiliary":{},"objectName":"T2000","dateCreated":"1451337180","dateModified":"1451337180","dependsOnTaskPHIDs":[]},"error_code":null,"error_info":null}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":5000,"phid":"PHID-TASK-1"},"transactions":[{"phid":"PHID-XACT-TASK-1"}]},"error_code":null,"error_info":null}`)
+	})
+
+	if _, err := client.Tasks.Delete(ctx, "5000", "Filed by mistake."); err != nil {
+		t.Errorf("Tasks.Delete returned error: %v", err)
+	}
+
+	if _, err := client.Tasks.Delete(ctx, "5001", ""); err != nil {
+		t.Errorf("Tasks.Delete returned error: %v", err)
+	}
+
+	if queries != 1 {
+		t.Errorf("maniphest.querystatuses was sent %d times, expected once", queries)
+	}
+}
+
+func TestTasks_Close(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.edit", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"T5000","transactions":[{"type":"status","value":"wontfix"},{"type":"comment","value":"Not something we will support."}]}`)
+		fmt.Fprint(w, `{"result":{"object":{"id":5000,"phid":"PHID-TASK-1"},"transactions":[{"phid":"PHID-XACT-TASK-1"},{"phid":"PHID-XACT-TASK-2"}]},"error_code":null,"error_info":null}`)
 	})
 
-	_, err := client.Tasks.Update(updateRequest)
+	result, _, err := client.Tasks.Close(ctx, "T5000", "wontfix", "Not something we will support.")
 	if err != nil {
-		t.Errorf("Tasks.Update returned error: %v", err)
+		t.Fatalf("Tasks.Close returned error: %v", err)
 	}
 
-	// Right now the Update interface doesn't return the updated object
+	if result.Object.PHID != "PHID-TASK-1" || len(result.Transactions) != 2 {
+		t.Errorf("Tasks.Close returned %+v", result)
+	}
+}
+
+func TestTasks_Relatives(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		constraints := conduitParams(t, r)["constraints"].(map[string]interface{})
+		if _, ok := constraints["subtaskIDs"]; ok {
+			testJSONParams(t, r, `{"constraints":{"subtaskIDs":[1000]}}`)
+			fmt.Fprint(w, `{"result":{"data":[{"id":900,"phid":"PHID-TASK-900","fields":{}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+			return
+		}
+
+		testJSONParams(t, r, `{"constraints":{"parentIDs":[1000]}}`)
+		fmt.Fprint(w, `{"result":{"data":[{"id":1001,"phid":"PHID-TASK-1001","fields":{}},{"id":1002,"phid":"PHID-TASK-1002","fields":{}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	task := &Task{ID: 1000, PHID: "PHID-TASK-1000"}
+	if _, err := client.Tasks.Relatives(ctx, task); err != nil {
+		t.Fatalf("Tasks.Relatives returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(task.Parents, []string{"PHID-TASK-900"}) || !reflect.DeepEqual(task.Subtasks, []string{"PHID-TASK-1001", "PHID-TASK-1002"}) {
+		t.Errorf("Tasks.Relatives found parents %v and subtasks %v", task.Parents, task.Subtasks)
+	}
+
+	if _, err := client.Tasks.Relatives(ctx, &Task{PHID: "PHID-TASK-1000"}); err == nil {
+		t.Errorf("Tasks.Relatives without a task ID returned no error")
+	}
+}
+
+func TestTimestamp_UnmarshalJSON(t *testing.T) {
+	cases := map[string]time.Time{
+		`1415646583`:             time.Unix(1415646583, 0),
+		`"1415646583"`:           time.Unix(1415646583, 0),
+		`"2014-11-10T19:09:43Z"`: time.Date(2014, 11, 10, 19, 9, 43, 0, time.UTC),
+	}
+
+	for data, expected := range cases {
+		var ts Timestamp
+		if err := ts.UnmarshalJSON([]byte(data)); err != nil || !ts.Time.Equal(expected) {
+			t.Errorf("Timestamp.UnmarshalJSON(%s) = %v, %v, expected %v", data, ts, err, expected)
+		}
+	}
+
+	var ts Timestamp
+	if err := ts.UnmarshalJSON([]byte(`"yesterday"`)); err == nil {
+		t.Errorf("Timestamp.UnmarshalJSON of a bad time returned no error")
+	}
+}
+
+func TestTaskCustomFields_UnmarshalJSON(t *testing.T) {
+	var fields TaskCustomFields
+	if err := fields.UnmarshalJSON([]byte(`{"std:maniphest:mycompany.estimate":"3","isdc:sprint:storypoints":5}`)); err != nil {
+		t.Fatalf("TaskCustomFields.UnmarshalJSON returned error: %v", err)
+	}
+
+	expected := TaskCustomFields{"mycompany.estimate": "3", "isdc:sprint:storypoints": float64(5)}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("TaskCustomFields.UnmarshalJSON decoded %+v, expected %+v", fields, expected)
+	}
 }
@@ -19,10 +19,16 @@ func (t Timestamp) String() string {
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
-// Time is expected in RFC3339 or Unix format.
+// Time is expected in RFC3339 or Unix format. Phabricator's older methods send
+// Unix times as strings, like "1415646583", which are accepted too.
 func (t *Timestamp) UnmarshalJSON(data []byte) error {
 	str := string(data)
-	i, err := strconv.ParseInt(str, 10, 64)
+	unix := str
+	if unquoted, err := strconv.Unquote(str); err == nil {
+		unix = unquoted
+	}
+
+	i, err := strconv.ParseInt(unix, 10, 64)
 	if err == nil {
 		t.Time = time.Unix(i, 0)
 	} else {
@@ -0,0 +1,469 @@
+package golph
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const transactionsSearchPath = "api/transaction.search"
+
+// TransactionsService is an interface for reading the timeline of an object:
+// the edits and comments made to it, and by whom.
+// See: https://secure.phabricator.com/conduit/method/transaction.search/
+type TransactionsService interface {
+	Search(context.Context, *TransactionSearchRequest) ([]AppliedTransaction, *Response, error)
+	SearchPager(context.Context, *TransactionSearchRequest) *Pager
+}
+
+// TransactionsServiceOp handles communication with the conduit methods
+type TransactionsServiceOp struct {
+	client *Client
+}
+
+var _ TransactionsService = &TransactionsServiceOp{}
+
+// Kinds of transaction that are decoded into the typed fields of AppliedTransaction.
+// Transactions of other kinds only have their raw Fields.
+const (
+	TransactionTypeCreate      = "create"
+	TransactionTypeTitle       = "title"
+	TransactionTypeDescription = "description"
+	TransactionTypeStatus      = "status"
+	TransactionTypeOwner       = "owner"
+	TransactionTypePriority    = "priority"
+	TransactionTypeProjects    = "projects"
+	TransactionTypeSubscribers = "subscribers"
+	TransactionTypeReviewers   = "reviewers"
+	TransactionTypeColumn      = "column"
+	TransactionTypeComment     = "comment"
+)
+
+// AppliedTransaction is a single change made to an object.
+/*
+{
+  "id": 1234,
+  "phid": "PHID-XACT-TASK-1",
+  "type": "status",
+  "authorPHID": "PHID-USER-1",
+  "objectPHID": "PHID-TASK-1",
+  "dateCreated": 1536000000,
+  "dateModified": 1536000000,
+  "groupID": "abc123",
+  "comments": [],
+  "fields": {"old": "open", "new": "resolved"}
+}
+*/
+type AppliedTransaction struct {
+	ID           int        `json:"id"`
+	PHID         string     `json:"phid"`
+	Type         string     `json:"type"`
+	AuthorPHID   string     `json:"authorPHID"`
+	ObjectPHID   string     `json:"objectPHID"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+
+	// GroupID is shared by transactions made in the same edit.
+	GroupID string `json:"groupID"`
+
+	Comments []TransactionComment `json:"comments,omitempty"`
+
+	// Old and New are the values before and after title, description,
+	// status, owner and priority transactions. Priorities are given by name,
+	// and an unassigned owner is empty.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+
+	// Operations lists the changes made by projects, subscribers and
+	// reviewers transactions.
+	Operations []TransactionOperation `json:"operations,omitempty"`
+
+	// Column describes a column transaction.
+	Column *ColumnChange `json:"column,omitempty"`
+
+	// Fields holds the raw fields of the transaction, whatever its kind.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (f AppliedTransaction) String() string {
+	return Stringify(f)
+}
+
+// TransactionComment is a comment left with a transaction. Edited comments have
+// a version for each edit, newest first.
+type TransactionComment struct {
+	ID           int        `json:"id"`
+	PHID         string     `json:"phid"`
+	Version      int        `json:"version"`
+	AuthorPHID   string     `json:"authorPHID"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+	Removed      bool       `json:"removed"`
+	Content      string     `json:"content"`
+}
+
+// TransactionOperation adds an object to a list, like a project to a task's
+// tags, removes one, or for reviewers updates one.
+type TransactionOperation struct {
+	Operation string `json:"operation"`
+	PHID      string `json:"phid"`
+
+	// Only set for reviewers
+	OldStatus  string `json:"oldStatus,omitempty"`
+	NewStatus  string `json:"newStatus,omitempty"`
+	IsBlocking bool   `json:"isBlocking,omitempty"`
+}
+
+// ColumnChange is a move of a task to a column on a project's workboard.
+type ColumnChange struct {
+	BoardPHID       string   `json:"boardPHID"`
+	ColumnPHID      string   `json:"columnPHID"`
+	FromColumnPHIDs []string `json:"fromColumnPHIDs"`
+}
+
+// TransactionSearchConstraints narrows down the results of transaction.search.
+type TransactionSearchConstraints struct {
+	PHIDs       []string `json:"phids,omitempty"`
+	AuthorPHIDs []string `json:"authorPHIDs,omitempty"`
+}
+
+// TransactionSearchRequest represents a request to transaction.search.
+// ObjectIdentifier is the PHID or monogram, like T123, of the object whose
+// transactions to list.
+type TransactionSearchRequest struct {
+	ObjectIdentifier string                        `json:"objectIdentifier"`
+	Constraints      *TransactionSearchConstraints `json:"constraints,omitempty"`
+	Before           string                        `json:"before,omitempty"`
+	After            string                        `json:"after,omitempty"`
+	Limit            int                           `json:"limit,omitempty"`
+}
+
+// transactionValue is an old or new value, which is a string, a PHID, null, or
+// for priorities an object with a value and a name.
+type transactionValue string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (v *transactionValue) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		value = object["name"]
+		if value == nil {
+			value = object["value"]
+		}
+	}
+
+	switch value := value.(type) {
+	case nil:
+		*v = ""
+	case string:
+		*v = transactionValue(value)
+	case float64:
+		*v = transactionValue(strconv.FormatFloat(value, 'f', -1, 64))
+	default:
+		*v = transactionValue(string(data))
+	}
+	return nil
+}
+
+// columnPHIDList is a list of column PHIDs, which PHP sends as a map of PHIDs
+// to themselves.
+type columnPHIDList []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *columnPHIDList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+
+	var byPHID map[string]string
+	if err := json.Unmarshal(data, &byPHID); err != nil {
+		return err
+	}
+
+	*l = columnPHIDList{}
+	for _, phid := range byPHID {
+		*l = append(*l, phid)
+	}
+	sort.Strings(*l)
+	return nil
+}
+
+type transactionSearchFields struct {
+	Old             transactionValue       `json:"old"`
+	New             transactionValue       `json:"new"`
+	Operations      []TransactionOperation `json:"operations"`
+	BoardPHID       string                 `json:"boardPHID"`
+	ColumnPHID      string                 `json:"columnPHID"`
+	FromColumnPHIDs columnPHIDList         `json:"fromColumnPHIDs"`
+}
+
+type transactionSearchComment struct {
+	ID           int        `json:"id"`
+	PHID         string     `json:"phid"`
+	Version      int        `json:"version"`
+	AuthorPHID   string     `json:"authorPHID"`
+	DateCreated  *Timestamp `json:"dateCreated"`
+	DateModified *Timestamp `json:"dateModified"`
+	Removed      bool       `json:"removed"`
+	Content      struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+}
+
+type transactionSearchData struct {
+	ID           int                        `json:"id"`
+	PHID         string                     `json:"phid"`
+	Type         string                     `json:"type"`
+	AuthorPHID   string                     `json:"authorPHID"`
+	ObjectPHID   string                     `json:"objectPHID"`
+	DateCreated  *Timestamp                 `json:"dateCreated"`
+	DateModified *Timestamp                 `json:"dateModified"`
+	GroupID      string                     `json:"groupID"`
+	Comments     []transactionSearchComment `json:"comments"`
+	Fields       json.RawMessage            `json:"fields"`
+}
+
+func (d transactionSearchData) toTransaction() (AppliedTransaction, error) {
+	transaction := AppliedTransaction{
+		ID:           d.ID,
+		PHID:         d.PHID,
+		Type:         d.Type,
+		AuthorPHID:   d.AuthorPHID,
+		ObjectPHID:   d.ObjectPHID,
+		DateCreated:  d.DateCreated,
+		DateModified: d.DateModified,
+		GroupID:      d.GroupID,
+	}
+
+	for _, comment := range d.Comments {
+		transaction.Comments = append(transaction.Comments, TransactionComment{
+			ID:           comment.ID,
+			PHID:         comment.PHID,
+			Version:      comment.Version,
+			AuthorPHID:   comment.AuthorPHID,
+			DateCreated:  comment.DateCreated,
+			DateModified: comment.DateModified,
+			Removed:      comment.Removed,
+			Content:      comment.Content.Raw,
+		})
+	}
+
+	if len(d.Fields) == 0 || isEmptyJSONArray(d.Fields) {
+		return transaction, nil
+	}
+
+	if err := json.Unmarshal(d.Fields, &transaction.Fields); err != nil {
+		return transaction, err
+	}
+
+	var fields transactionSearchFields
+	if err := json.Unmarshal(d.Fields, &fields); err != nil {
+		return transaction, err
+	}
+
+	switch d.Type {
+	case TransactionTypeTitle, TransactionTypeDescription, TransactionTypeStatus, TransactionTypeOwner, TransactionTypePriority:
+		transaction.Old = string(fields.Old)
+		transaction.New = string(fields.New)
+	case TransactionTypeProjects, TransactionTypeSubscribers, TransactionTypeReviewers:
+		transaction.Operations = fields.Operations
+	case TransactionTypeColumn:
+		transaction.Column = &ColumnChange{
+			BoardPHID:       fields.BoardPHID,
+			ColumnPHID:      fields.ColumnPHID,
+			FromColumnPHIDs: fields.FromColumnPHIDs,
+		}
+	}
+
+	return transaction, nil
+}
+
+type TransactionSearchResult struct {
+	Data   []transactionSearchData `json:"data"`
+	Cursor PhabricatorCursor       `json:"cursor"`
+}
+
+type TransactionSearchResponse struct {
+	Result    TransactionSearchResult `json:"result"`
+	ErrorCode string                  `json:"error_code,omitempty"`
+	ErrorInfo string                  `json:"error_info,omitempty"`
+}
+
+// toTransactions converts a page of transaction.search results.
+func (r *TransactionSearchResponse) toTransactions() ([]AppliedTransaction, error) {
+	var list []AppliedTransaction
+	for _, data := range r.Result.Data {
+		transaction, err := data.toTransaction()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, transaction)
+	}
+	return list, nil
+}
+
+// Search lists the transactions of an object, newest first (through
+// transaction.search).
+func (f *TransactionsServiceOp) Search(ctx context.Context, searchRequest *TransactionSearchRequest) ([]AppliedTransaction, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", transactionsSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(TransactionSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	list, err := root.toTransactions()
+	return list, resp, err
+}
+
+// SearchPager returns a Pager that follows the transaction.search cursor
+// through every page of results. Items are AppliedTransaction values.
+func (f *TransactionsServiceOp) SearchPager(ctx context.Context, searchRequest *TransactionSearchRequest) *Pager {
+	page := TransactionSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", transactionsSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(TransactionSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		list, err := root.toTransactions()
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, transaction := range list {
+			items = append(items, transaction)
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
+
+// RewindTask returns task as it was at the given time, undoing the changes its
+// transactions made after then to its title, description, status, owner,
+// priority, projects, subscribers and workboard columns. Other fields are left
+// as they are now. It returns nil if the task had not been created by then.
+func RewindTask(task Task, transactions []AppliedTransaction, at time.Time) *Task {
+	if task.DateCreated != nil && task.DateCreated.After(at) {
+		return nil
+	}
+
+	newestFirst := make([]AppliedTransaction, len(transactions))
+	copy(newestFirst, transactions)
+	sort.Stable(transactionsNewestFirst(newestFirst))
+
+	rewound := task
+	rewound.Projects = append([]string(nil), task.Projects...)
+	rewound.CCs = append([]string(nil), task.CCs...)
+	if task.Columns != nil {
+		rewound.Columns = map[string][]TaskColumn{}
+		for board, columns := range task.Columns {
+			rewound.Columns[board] = append([]TaskColumn(nil), columns...)
+		}
+	}
+
+	for _, transaction := range newestFirst {
+		if transaction.DateCreated == nil || !transaction.DateCreated.After(at) {
+			break
+		}
+
+		switch transaction.Type {
+		case TransactionTypeCreate:
+			return nil
+		case TransactionTypeTitle:
+			rewound.Title = transaction.Old
+		case TransactionTypeDescription:
+			rewound.Description = transaction.Old
+		case TransactionTypeStatus:
+			rewound.Status = transaction.Old
+			rewound.StatusName = ""
+		case TransactionTypeOwner:
+			rewound.Owner = transaction.Old
+		case TransactionTypePriority:
+			rewound.Priority = transaction.Old
+			rewound.PriorityColor = ""
+		case TransactionTypeProjects:
+			rewound.Projects = undoOperations(rewound.Projects, transaction.Operations)
+		case TransactionTypeSubscribers:
+			rewound.CCs = undoOperations(rewound.CCs, transaction.Operations)
+		case TransactionTypeColumn:
+			// Without its fields there is no telling where the task moved from.
+			if transaction.Column == nil {
+				continue
+			}
+			if rewound.Columns == nil {
+				rewound.Columns = map[string][]TaskColumn{}
+			}
+			var columns []TaskColumn
+			for _, phid := range transaction.Column.FromColumnPHIDs {
+				columns = append(columns, TaskColumn{PHID: phid})
+			}
+			rewound.Columns[transaction.Column.BoardPHID] = columns
+		}
+	}
+
+	return &rewound
+}
+
+// undoOperations reverses add and remove operations made to a list of PHIDs.
+func undoOperations(phids []string, operations []TransactionOperation) []string {
+	for i := len(operations) - 1; i >= 0; i-- {
+		switch operation := operations[i]; operation.Operation {
+		case "add":
+			phids = removePHID(phids, operation.PHID)
+		case "remove":
+			phids = append(removePHID(phids, operation.PHID), operation.PHID)
+		}
+	}
+	return phids
+}
+
+func removePHID(phids []string, phid string) []string {
+	kept := phids[:0]
+	for _, p := range phids {
+		if p != phid {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// transactionsNewestFirst orders transactions the way transaction.search
+// returns them.
+type transactionsNewestFirst []AppliedTransaction
+
+func (t transactionsNewestFirst) Len() int      { return len(t) }
+func (t transactionsNewestFirst) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t transactionsNewestFirst) Less(i, j int) bool {
+	if t[i].DateCreated == nil || t[j].DateCreated == nil {
+		return t[i].DateCreated != nil
+	}
+	if !t[i].DateCreated.Equal(*t[j].DateCreated) {
+		return t[i].DateCreated.After(t[j].DateCreated.Time)
+	}
+	return t[i].ID > t[j].ID
+}
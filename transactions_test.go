@@ -0,0 +1,228 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const searchTransactionsJSON = `{"result":{"data":[
+{"id":6,"phid":"PHID-XACT-TASK-6","type":"column","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000600,"dateModified":1536000600,"groupID":"g4","comments":[],"fields":{"boardPHID":"PHID-PROJ-1","columnPHID":"PHID-PCOL-2","fromColumnPHIDs":{"PHID-PCOL-1":"PHID-PCOL-1"}}},
+{"id":5,"phid":"PHID-XACT-TASK-5","type":"projects","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000500,"dateModified":1536000500,"groupID":"g3","comments":[],"fields":{"operations":[{"operation":"add","phid":"PHID-PROJ-2"},{"operation":"remove","phid":"PHID-PROJ-3"}]}},
+{"id":4,"phid":"PHID-XACT-TASK-4","type":"owner","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000400,"dateModified":1536000400,"groupID":"g2","comments":[],"fields":{"old":null,"new":"PHID-USER-2"}},
+{"id":3,"phid":"PHID-XACT-TASK-3","type":"priority","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000300,"dateModified":1536000300,"groupID":"g2","comments":[],"fields":{"old":{"value":90,"name":"Needs Triage"},"new":{"value":80,"name":"High"}}},
+{"id":2,"phid":"PHID-XACT-TASK-2","type":"comment","authorPHID":"PHID-USER-2","objectPHID":"PHID-TASK-1","dateCreated":1536000200,"dateModified":1536000200,"groupID":"g1","comments":[{"id":10,"phid":"PHID-XCMT-1","version":2,"authorPHID":"PHID-USER-2","dateCreated":1536000250,"dateModified":1536000250,"removed":false,"content":{"raw":"Looking into it"}}],"fields":[]},
+{"id":1,"phid":"PHID-XACT-TASK-1","type":"status","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000100,"dateModified":1536000100,"groupID":"g0","comments":[],"fields":{"old":"open","new":"resolved"}}
+],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`
+
+func TestTransactions_Search(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/transaction.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"objectIdentifier":"T1","constraints":{"authorPHIDs":["PHID-USER-1"]}}`)
+		fmt.Fprint(w, searchTransactionsJSON)
+	})
+
+	transactions, _, err := client.Transactions.Search(ctx, &TransactionSearchRequest{
+		ObjectIdentifier: "T1",
+		Constraints:      &TransactionSearchConstraints{AuthorPHIDs: []string{"PHID-USER-1"}},
+	})
+	if err != nil {
+		t.Fatalf("Transactions.Search returned error: %v", err)
+	}
+
+	if len(transactions) != 6 {
+		t.Fatalf("Transactions.Search returned %d transactions, expected 6", len(transactions))
+	}
+
+	column := transactions[0]
+	expectedColumn := &ColumnChange{BoardPHID: "PHID-PROJ-1", ColumnPHID: "PHID-PCOL-2", FromColumnPHIDs: []string{"PHID-PCOL-1"}}
+	if !reflect.DeepEqual(column.Column, expectedColumn) {
+		t.Errorf("Column transaction is %+v, expected %+v", column.Column, expectedColumn)
+	}
+
+	projects := transactions[1]
+	expectedOperations := []TransactionOperation{{Operation: "add", PHID: "PHID-PROJ-2"}, {Operation: "remove", PHID: "PHID-PROJ-3"}}
+	if !reflect.DeepEqual(projects.Operations, expectedOperations) {
+		t.Errorf("Projects transaction operations are %+v, expected %+v", projects.Operations, expectedOperations)
+	}
+
+	if owner := transactions[2]; owner.Old != "" || owner.New != "PHID-USER-2" {
+		t.Errorf("Owner transaction went from %q to %q, expected from \"\" to PHID-USER-2", owner.Old, owner.New)
+	}
+
+	if priority := transactions[3]; priority.Old != "Needs Triage" || priority.New != "High" {
+		t.Errorf("Priority transaction went from %q to %q, expected from Needs Triage to High", priority.Old, priority.New)
+	}
+
+	expectedComment := []TransactionComment{{
+		ID:           10,
+		PHID:         "PHID-XCMT-1",
+		Version:      2,
+		AuthorPHID:   "PHID-USER-2",
+		DateCreated:  &Timestamp{time.Unix(1536000250, 0)},
+		DateModified: &Timestamp{time.Unix(1536000250, 0)},
+		Content:      "Looking into it",
+	}}
+	if comment := transactions[4]; !reflect.DeepEqual(comment.Comments, expectedComment) || comment.Fields != nil {
+		t.Errorf("Comment transaction is %+v, expected comments %+v and no fields", comment, expectedComment)
+	}
+
+	expectedStatus := AppliedTransaction{
+		ID:           1,
+		PHID:         "PHID-XACT-TASK-1",
+		Type:         TransactionTypeStatus,
+		AuthorPHID:   "PHID-USER-1",
+		ObjectPHID:   "PHID-TASK-1",
+		DateCreated:  &Timestamp{time.Unix(1536000100, 0)},
+		DateModified: &Timestamp{time.Unix(1536000100, 0)},
+		GroupID:      "g0",
+		Old:          "open",
+		New:          "resolved",
+		Fields:       map[string]interface{}{"old": "open", "new": "resolved"},
+	}
+	if !reflect.DeepEqual(transactions[5], expectedStatus) {
+		t.Errorf("Status transaction is:\n%+v\nExpected:\n%+v", transactions[5], expectedStatus)
+	}
+}
+
+func TestTransactions_SearchPager(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/transaction.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		after, _ := conduitParams(t, r)["after"].(string)
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"result":{"data":[{"id":2,"phid":"PHID-XACT-TASK-2","type":"title","fields":{"old":"Old","new":"New"}}],"cursor":{"limit":1,"after":"2","before":null}},"error_code":null,"error_info":null}`)
+		case "2":
+			fmt.Fprint(w, `{"result":{"data":[{"id":1,"phid":"PHID-XACT-TASK-1","type":"create","fields":[]}],"cursor":{"limit":1,"after":null,"before":"1"}},"error_code":null,"error_info":null}`)
+		default:
+			t.Errorf("Unexpected cursor %q", after)
+		}
+	})
+
+	var phids []string
+	pager := client.Transactions.SearchPager(ctx, &TransactionSearchRequest{ObjectIdentifier: "T1", Limit: 1})
+	for pager.Next() {
+		phids = append(phids, pager.Item().(AppliedTransaction).PHID)
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("SearchPager returned error: %v", err)
+	}
+
+	if expected := []string{"PHID-XACT-TASK-2", "PHID-XACT-TASK-1"}; !reflect.DeepEqual(phids, expected) {
+		t.Errorf("SearchPager returned %v, expected %v", phids, expected)
+	}
+}
+
+func TestRewindTask(t *testing.T) {
+	task := Task{
+		PHID:        "PHID-TASK-1",
+		Title:       "New",
+		Status:      "resolved",
+		StatusName:  "Resolved",
+		Owner:       "PHID-USER-2",
+		Priority:    "High",
+		Projects:    []string{"PHID-PROJ-1", "PHID-PROJ-2"},
+		CCs:         []string{"PHID-USER-1"},
+		Columns:     map[string][]TaskColumn{"PHID-PROJ-1": {{PHID: "PHID-PCOL-2", Name: "Done"}}},
+		DateCreated: &Timestamp{time.Unix(1536000000, 0)},
+	}
+
+	transactions := []AppliedTransaction{
+		{ID: 6, Type: TransactionTypeColumn, DateCreated: &Timestamp{time.Unix(1536000600, 0)}},
+		{ID: 5, Type: TransactionTypeColumn, DateCreated: &Timestamp{time.Unix(1536000500, 0)}, Column: &ColumnChange{BoardPHID: "PHID-PROJ-1", ColumnPHID: "PHID-PCOL-2", FromColumnPHIDs: []string{"PHID-PCOL-1"}}},
+		{ID: 4, Type: TransactionTypeProjects, DateCreated: &Timestamp{time.Unix(1536000400, 0)}, Operations: []TransactionOperation{{Operation: "add", PHID: "PHID-PROJ-2"}, {Operation: "remove", PHID: "PHID-PROJ-3"}}},
+		{ID: 3, Type: TransactionTypeStatus, DateCreated: &Timestamp{time.Unix(1536000300, 0)}, Old: "open", New: "resolved"},
+		{ID: 2, Type: TransactionTypeTitle, DateCreated: &Timestamp{time.Unix(1536000200, 0)}, Old: "Old", New: "New"},
+		{ID: 1, Type: TransactionTypeCreate, DateCreated: &Timestamp{time.Unix(1536000000, 0)}},
+	}
+
+	rewound := RewindTask(task, transactions, time.Unix(1536000250, 0))
+	expected := &Task{
+		PHID:        "PHID-TASK-1",
+		Title:       "New",
+		Status:      "open",
+		Owner:       "PHID-USER-2",
+		Priority:    "High",
+		Projects:    []string{"PHID-PROJ-1", "PHID-PROJ-3"},
+		CCs:         []string{"PHID-USER-1"},
+		Columns:     map[string][]TaskColumn{"PHID-PROJ-1": {{PHID: "PHID-PCOL-1"}}},
+		DateCreated: &Timestamp{time.Unix(1536000000, 0)},
+	}
+	if !reflect.DeepEqual(rewound, expected) {
+		t.Errorf("RewindTask returned:\n%+v\nExpected:\n%+v", rewound, expected)
+	}
+
+	if task.Status != "resolved" || !reflect.DeepEqual(task.Projects, []string{"PHID-PROJ-1", "PHID-PROJ-2"}) {
+		t.Errorf("RewindTask changed the task it was given: %+v", task)
+	}
+
+	if rewound := RewindTask(task, transactions, time.Unix(1536000100, 0)); rewound == nil || rewound.Title != "Old" {
+		t.Errorf("RewindTask returned %+v, expected the title to be rewound", rewound)
+	}
+
+	if rewound := RewindTask(task, transactions, time.Unix(1535000000, 0)); rewound != nil {
+		t.Errorf("RewindTask returned %+v before the task was created, expected nil", rewound)
+	}
+}
+
+func TestTasks_AtColumnWithoutFields(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[{"id":1,"type":"TASK","phid":"PHID-TASK-1","fields":{"name":"Task","description":{"raw":""},"status":{"value":"open","name":"Open","color":null},"priority":{"value":80,"subpriority":0,"name":"High","color":"red"},"points":null,"dateCreated":1536000000,"dateModified":1536000600},"attachments":{"columns":{"boards":{"PHID-PROJ-1":{"columns":[{"id":2,"phid":"PHID-PCOL-2","name":"Done"}]}}},"projects":{"projectPHIDs":["PHID-PROJ-1"]},"subscribers":{"subscriberPHIDs":[]}}}],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/transaction.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[{"id":2,"phid":"PHID-XACT-TASK-2","type":"column","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000600,"dateModified":1536000600,"groupID":"g2","comments":[],"fields":[]},{"id":1,"phid":"PHID-XACT-TASK-1","type":"create","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000000,"dateModified":1536000000,"groupID":"g1","comments":[],"fields":[]}],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	task, _, err := client.Tasks.At(ctx, "PHID-TASK-1", time.Unix(1536000300, 0))
+	if err != nil {
+		t.Fatalf("Tasks.At returned error: %v", err)
+	}
+
+	if expected := []TaskColumn{{ID: 2, PHID: "PHID-PCOL-2", Name: "Done"}}; !reflect.DeepEqual(task.Columns["PHID-PROJ-1"], expected) {
+		t.Errorf("Tasks.At returned columns %+v, expected the task left in %+v", task.Columns, expected)
+	}
+}
+
+func TestTasks_At(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"result":{"data":[{"id":1,"type":"TASK","phid":"PHID-TASK-1","fields":{"name":"Task","description":{"raw":""},"authorPHID":"PHID-USER-1","ownerPHID":"PHID-USER-2","status":{"value":"resolved","name":"Resolved","color":null},"priority":{"value":80,"subpriority":0,"name":"High","color":"red"},"points":null,"dateCreated":1536000000,"dateModified":1536000600},"attachments":{"columns":{"boards":[]},"projects":{"projectPHIDs":[]},"subscribers":{"subscriberPHIDs":[]}}}],"cursor":{"limit":100,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/transaction.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		params := conduitParams(t, r)
+		if params["objectIdentifier"] != "PHID-TASK-1" {
+			t.Errorf("transaction.search objectIdentifier = %v, expected PHID-TASK-1", params["objectIdentifier"])
+		}
+		if params["after"] != nil {
+			t.Errorf("transaction.search was asked for another page after reaching the time")
+		}
+		fmt.Fprint(w, strings.Replace(searchTransactionsJSON, `"after":null`, `"after":"1"`, 1))
+	})
+
+	task, _, err := client.Tasks.At(ctx, "PHID-TASK-1", time.Unix(1536000350, 0))
+	if err != nil {
+		t.Fatalf("Tasks.At returned error: %v", err)
+	}
+
+	if task.Status != "resolved" || task.Owner != "" || task.Priority != "High" {
+		t.Errorf("Tasks.At returned status %q, owner %q and priority %q, expected resolved, no owner and High", task.Status, task.Owner, task.Priority)
+	}
+}
@@ -0,0 +1,271 @@
+package golph
+
+import (
+	"context"
+	"strings"
+)
+
+const usersQueryPath = "api/user.query"
+const usersSearchPath = "api/user.search"
+const usersWhoAmIPath = "api/user.whoami"
+
+// UsersService is an interface for interfacing with Users
+// See: https://secure.phabricator.com/conduit/ (and search for user)
+type UsersService interface {
+	List(context.Context, *ListOptions) ([]User, *Response, error)
+	ListPager(context.Context, *ListOptions) *Pager
+	Get(context.Context, string) (*User, *Response, error)
+	WhoAmI(context.Context) (*User, *Response, error)
+	Search(context.Context, *UserSearchRequest) ([]User, *Response, error)
+	SearchPager(context.Context, *UserSearchRequest) *Pager
+}
+
+// UsersServiceOp handles communication with the conduit methods
+type UsersServiceOp struct {
+	client *Client
+}
+
+var _ UsersService = &UsersServiceOp{}
+
+// User represents a Phabricator user.
+/*
+user.query and user.whoami return:
+{
+  "phid": "PHID-USER-1",
+  "userName": "alice",
+  "realName": "Alice Example",
+  "image": "https://phabricator.example.com/file/data/.../profile",
+  "uri": "https://phabricator.example.com/p/alice/",
+  "roles": ["verified", "approved", "activated"],
+  "primaryEmail": "alice@example.com"
+}
+
+user.search nests most of this under "fields" and adds "id" and the dates.
+*/
+type User struct {
+	ID           int        `json:"id"`
+	PHID         PHID       `json:"phid"`
+	Username     string     `json:"userName"`
+	RealName     string     `json:"realName"`
+	Image        string     `json:"image"`
+	URI          string     `json:"uri"`
+	Roles        []string   `json:"roles"`
+	PrimaryEmail string     `json:"primaryEmail"`
+	DateCreated  *Timestamp `json:"dateCreated,omitempty"`
+	DateModified *Timestamp `json:"dateModified,omitempty"`
+}
+
+func (f User) String() string {
+	return Stringify(f)
+}
+
+// HasRole returns true if the user has the given role, such as "admin",
+// "disabled", "bot", "list", "verified", "approved" or "activated".
+func (f User) HasRole(role string) bool {
+	for _, r := range f.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// UserSearchConstraints narrows down the results of a user.search call.
+type UserSearchConstraints struct {
+	IDs           []int    `json:"ids,omitempty"`
+	PHIDs         []string `json:"phids,omitempty"`
+	Usernames     []string `json:"usernames,omitempty"`
+	NameLike      string   `json:"nameLike,omitempty"`
+	IsAdmin       *bool    `json:"isAdmin,omitempty"`
+	IsDisabled    *bool    `json:"isDisabled,omitempty"`
+	IsBot         *bool    `json:"isBot,omitempty"`
+	IsMailingList *bool    `json:"isMailingList,omitempty"`
+	Query         string   `json:"query,omitempty"`
+}
+
+// UserSearchRequest represents a request to user.search.
+type UserSearchRequest struct {
+	QueryKey    string                 `json:"queryKey,omitempty"`
+	Constraints *UserSearchConstraints `json:"constraints,omitempty"`
+	Order       string                 `json:"order,omitempty"`
+	Before      string                 `json:"before,omitempty"`
+	After       string                 `json:"after,omitempty"`
+	Limit       int                    `json:"limit,omitempty"`
+}
+
+type userSearchFields struct {
+	Username     string     `json:"username"`
+	RealName     string     `json:"realName"`
+	Roles        []string   `json:"roles"`
+	DateCreated  *Timestamp `json:"dateCreated"`
+	DateModified *Timestamp `json:"dateModified"`
+}
+
+type userSearchData struct {
+	ID     int              `json:"id"`
+	PHID   PHID             `json:"phid"`
+	Fields userSearchFields `json:"fields"`
+}
+
+func (d userSearchData) toUser() User {
+	return User{
+		ID:           d.ID,
+		PHID:         d.PHID,
+		Username:     d.Fields.Username,
+		RealName:     d.Fields.RealName,
+		Roles:        d.Fields.Roles,
+		DateCreated:  d.Fields.DateCreated,
+		DateModified: d.Fields.DateModified,
+	}
+}
+
+type UserSearchResult struct {
+	Data   []userSearchData  `json:"data"`
+	Cursor PhabricatorCursor `json:"cursor"`
+}
+
+type UserSearchResponse struct {
+	Result    UserSearchResult `json:"result"`
+	ErrorCode string           `json:"error_code,omitempty"`
+	ErrorInfo string           `json:"error_info,omitempty"`
+}
+
+type UserQueryResponse struct {
+	Result    []User `json:"result"`
+	ErrorCode string `json:"error_code,omitempty"`
+	ErrorInfo string `json:"error_info,omitempty"`
+}
+
+type SingleUserResponse struct {
+	Result    User   `json:"result"`
+	ErrorCode string `json:"error_code,omitempty"`
+	ErrorInfo string `json:"error_info,omitempty"`
+}
+
+// List users (through user.query), paging by offset until the Limit or
+// MaxResults of opt is reached, or every user has been read.
+func (f *UsersServiceOp) List(ctx context.Context, opt *ListOptions) ([]User, *Response, error) {
+	pager := f.ListPager(ctx, opt)
+
+	var list []User
+	for pager.Next() {
+		list = append(list, pager.Item().(User))
+	}
+	return list, pager.Response(), pager.Err()
+}
+
+// ListPager returns a Pager over all users. Items are User values.
+func (f *UsersServiceOp) ListPager(ctx context.Context, opt *ListOptions) *Pager {
+	return newPagerFor(ctx, opt, offsetPageFunc(opt, func(ctx context.Context, page *ListOptions) ([]interface{}, *Response, error) {
+		path := usersQueryPath
+		path, err := addOptions(path, page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req, err := f.client.NewRequest(ctx, "POST", path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		root := new(UserQueryResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		var items []interface{}
+		for _, user := range root.Result {
+			items = append(items, user)
+		}
+		return items, resp, err
+	}))
+}
+
+// Get an individual user by username or PHID. It returns an error that
+// IsNotFound recognizes if there is no such user.
+func (f *UsersServiceOp) Get(ctx context.Context, name string) (*User, *Response, error) {
+	constraints := &UserSearchConstraints{}
+	if strings.HasPrefix(name, "PHID-USER-") {
+		constraints.PHIDs = []string{name}
+	} else {
+		constraints.Usernames = []string{name}
+	}
+
+	list, resp, err := f.Search(ctx, &UserSearchRequest{Constraints: constraints, Limit: 1})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if len(list) < 1 {
+		return nil, resp, notFoundError(resp, "user %s was not found", name)
+	}
+
+	return &list[0], resp, err
+}
+
+// WhoAmI returns the user the API token belongs to.
+func (f *UsersServiceOp) WhoAmI(ctx context.Context) (*User, *Response, error) {
+	req, err := f.client.NewRequest(ctx, "POST", usersWhoAmIPath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(SingleUserResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &root.Result, resp, err
+}
+
+// Search for users (through user.search).
+func (f *UsersServiceOp) Search(ctx context.Context, searchRequest *UserSearchRequest) ([]User, *Response, error) {
+	req, err := f.client.NewJSONRequest(ctx, "POST", usersSearchPath, searchRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := new(UserSearchResponse)
+	resp, err := f.client.Do(req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var list []User
+	for _, data := range root.Result.Data {
+		list = append(list, data.toUser())
+	}
+	return list, resp, err
+}
+
+// SearchPager returns a Pager that follows the user.search cursor through
+// every page of results. Items are User values.
+func (f *UsersServiceOp) SearchPager(ctx context.Context, searchRequest *UserSearchRequest) *Pager {
+	page := UserSearchRequest{}
+	if searchRequest != nil {
+		page = *searchRequest
+	}
+
+	return NewPager(ctx, func(ctx context.Context, after string) ([]interface{}, string, *Response, error) {
+		page.After = after
+
+		req, err := f.client.NewJSONRequest(ctx, "POST", usersSearchPath, &page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		root := new(UserSearchResponse)
+		resp, err := f.client.Do(req, root)
+		if err != nil {
+			return nil, "", resp, err
+		}
+
+		var items []interface{}
+		for _, data := range root.Result.Data {
+			items = append(items, data.toUser())
+		}
+		return items, root.Result.Cursor.After, resp, err
+	})
+}
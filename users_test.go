@@ -0,0 +1,200 @@
+package golph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	searchUserJSON = `{"result":{"data":[{"id":1,"type":"USER","phid":"PHID-USER-1","fields":{"username":"alice","realName":"Alice Example","roles":["admin","verified","approved","activated"],"dateCreated":1415646583,"dateModified":1451336014,"policy":{"view":"public","edit":"no-one"}},"attachments":{}}],"maps":{},"query":{"queryKey":null},"cursor":{"limit":100,"after":null,"before":null,"order":null}},"error_code":null,"error_info":null}`
+	whoAmIJSON     = `{"result":{"phid":"PHID-USER-1","userName":"alice","realName":"Alice Example","image":"https://phabricator.example.com/res/profile.png","uri":"https://phabricator.example.com/p/alice/","roles":["admin","verified","approved","activated"],"primaryEmail":"alice@example.com"},"error_code":null,"error_info":null}`
+)
+
+func TestUsers_ListUsers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.query", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"result":[{"phid":"PHID-USER-1","userName":"alice","realName":"Alice Example","image":"https://phabricator.example.com/res/profile.png","uri":"https://phabricator.example.com/p/alice/","roles":["verified","approved","activated"]},{"phid":"PHID-USER-2","userName":"bob","realName":"Bob Example","image":"https://phabricator.example.com/res/profile.png","uri":"https://phabricator.example.com/p/bob/","roles":["disabled"]}],"error_code":null,"error_info":null}`)
+	})
+
+	users, _, err := client.Users.List(ctx, nil)
+	if err != nil {
+		t.Errorf("Users.List returned error: %v", err)
+	}
+
+	expected := []User{
+		{PHID: "PHID-USER-1", Username: "alice", RealName: "Alice Example", Image: "https://phabricator.example.com/res/profile.png", URI: "https://phabricator.example.com/p/alice/", Roles: []string{"verified", "approved", "activated"}},
+		{PHID: "PHID-USER-2", Username: "bob", RealName: "Bob Example", Image: "https://phabricator.example.com/res/profile.png", URI: "https://phabricator.example.com/p/bob/", Roles: []string{"disabled"}},
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("Users.List returned %+v, expected %+v", users, expected)
+	}
+}
+
+func TestUsers_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"usernames":["alice"]},"limit":1}`)
+		fmt.Fprint(w, searchUserJSON)
+	})
+
+	user, _, err := client.Users.Get(ctx, "alice")
+	if err != nil {
+		t.Errorf("Users.Get returned error: %v", err)
+	}
+
+	expected := &User{
+		ID:           1,
+		PHID:         "PHID-USER-1",
+		Username:     "alice",
+		RealName:     "Alice Example",
+		Roles:        []string{"admin", "verified", "approved", "activated"},
+		DateCreated:  &Timestamp{time.Unix(1415646583, 0)},
+		DateModified: &Timestamp{time.Unix(1451336014, 0)},
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Users.Get returned %+v, expected %+v", user, expected)
+	}
+}
+
+func TestUsers_GetByPHID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"phids":["PHID-USER-1"]},"limit":1}`)
+		fmt.Fprint(w, searchUserJSON)
+	})
+
+	user, _, err := client.Users.Get(ctx, "PHID-USER-1")
+	if err != nil {
+		t.Errorf("Users.Get returned error: %v", err)
+	}
+
+	if user == nil || user.Username != "alice" {
+		t.Errorf("Users.Get returned %+v, expected alice", user)
+	}
+}
+
+func TestUsers_GetNotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[],"cursor":{"limit":1,"after":null,"before":null}},"error_code":null,"error_info":null}`)
+	})
+
+	user, _, err := client.Users.Get(ctx, "mallory")
+	if !IsNotFound(err) {
+		t.Errorf("Users.Get returned error %v, expected a not found error", err)
+	}
+	if user != nil {
+		t.Errorf("Users.Get returned %+v, expected nil", user)
+	}
+	if expected := "user.search: ERR-NOT-FOUND user mallory was not found"; err != nil && err.Error() != expected {
+		t.Errorf("Users.Get returned error %q, expected %q", err, expected)
+	}
+}
+
+func TestUsers_WhoAmI(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.whoami", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, whoAmIJSON)
+	})
+
+	user, _, err := client.Users.WhoAmI(ctx)
+	if err != nil {
+		t.Errorf("Users.WhoAmI returned error: %v", err)
+	}
+
+	expected := &User{
+		PHID:         "PHID-USER-1",
+		Username:     "alice",
+		RealName:     "Alice Example",
+		Image:        "https://phabricator.example.com/res/profile.png",
+		URI:          "https://phabricator.example.com/p/alice/",
+		Roles:        []string{"admin", "verified", "approved", "activated"},
+		PrimaryEmail: "alice@example.com",
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Users.WhoAmI returned %+v, expected %+v", user, expected)
+	}
+
+	if !user.HasRole("admin") {
+		t.Errorf("User.HasRole(admin) = false, expected true")
+	}
+}
+
+func TestUsers_Search(t *testing.T) {
+	setup()
+	defer teardown()
+
+	searchRequest := &UserSearchRequest{
+		Constraints: &UserSearchConstraints{
+			Usernames:     []string{"alice", "bob"},
+			IsAdmin:       Bool(true),
+			IsDisabled:    Bool(false),
+			IsBot:         Bool(false),
+			IsMailingList: Bool(false),
+		},
+		Limit: 10,
+	}
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONParams(t, r, `{"constraints":{"usernames":["alice","bob"],"isAdmin":true,"isDisabled":false,"isBot":false,"isMailingList":false},"limit":10}`)
+		fmt.Fprint(w, searchUserJSON)
+	})
+
+	users, _, err := client.Users.Search(ctx, searchRequest)
+	if err != nil {
+		t.Errorf("Users.Search returned error: %v", err)
+	}
+
+	if len(users) != 1 || users[0].PHID != "PHID-USER-1" {
+		t.Errorf("Users.Search returned %+v, expected PHID-USER-1", users)
+	}
+}
+
+func TestUsers_SearchPager(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/user.search", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		after, _ := conduitParams(t, r)["after"].(string)
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"result":{"data":[{"id":1,"phid":"PHID-USER-1","fields":{"username":"alice"}}],"cursor":{"limit":1,"after":"1","before":null}},"error_code":null,"error_info":null}`)
+		case "1":
+			fmt.Fprint(w, `{"result":{"data":[{"id":2,"phid":"PHID-USER-2","fields":{"username":"bob"}}],"cursor":{"limit":1,"after":null,"before":"2"}},"error_code":null,"error_info":null}`)
+		default:
+			t.Errorf("Unexpected cursor %q", after)
+		}
+	})
+
+	var usernames []string
+	pager := client.Users.SearchPager(ctx, &UserSearchRequest{Limit: 1})
+	for pager.Next() {
+		usernames = append(usernames, pager.Item().(User).Username)
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("SearchPager returned error: %v", err)
+	}
+
+	if expected := []string{"alice", "bob"}; !reflect.DeepEqual(usernames, expected) {
+		t.Errorf("SearchPager returned %v, expected %v", usernames, expected)
+	}
+}
@@ -0,0 +1,291 @@
+// Package webhook receives Phabricator webhooks, which Herald rules and
+// firehose hooks send whenever an object changes.
+//
+// See: https://secure.phabricator.com/book/phabricator/article/webhooks/
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jshirley/golph"
+)
+
+// SignatureHeader is the header Phabricator signs webhook requests with.
+const SignatureHeader = "X-Phabricator-Webhook-Signature"
+
+// maxPayloadSize is the largest request body the handler reads.
+const maxPayloadSize = 1 << 20
+
+// Payload is the body of a webhook request.
+/*
+{
+  "object": {"type": "TASK", "phid": "PHID-TASK-1"},
+  "triggers": [{"phid": "PHID-HRUL-1"}],
+  "action": {"test": false, "silent": false, "secure": false, "epoch": 1536000000},
+  "transactions": [{"phid": "PHID-XACT-TASK-1"}]
+}
+*/
+type Payload struct {
+	Object       Object    `json:"object"`
+	Triggers     []PHIDRef `json:"triggers"`
+	Action       Action    `json:"action"`
+	Transactions []PHIDRef `json:"transactions"`
+}
+
+// Object is the object that changed. Type is one of the golph.PHIDType
+// constants, like golph.PHIDTypeTask.
+type Object struct {
+	Type string `json:"type"`
+	PHID string `json:"phid"`
+}
+
+// PHIDRef refers to a Herald rule or a transaction.
+type PHIDRef struct {
+	PHID string `json:"phid"`
+}
+
+// Action describes the edit that triggered the webhook.
+type Action struct {
+	// Test is set for requests sent with the "Send Test Request" button.
+	Test bool `json:"test"`
+
+	// Silent is set for edits made without sending notifications.
+	Silent bool `json:"silent"`
+
+	// Secure is set when the hook may only be sent details over a secure
+	// channel.
+	Secure bool `json:"secure"`
+
+	Epoch int64 `json:"epoch"`
+}
+
+// TransactionPHIDs returns the PHIDs of the transactions in the payload.
+func (p *Payload) TransactionPHIDs() []string {
+	phids := make([]string, 0, len(p.Transactions))
+	for _, transaction := range p.Transactions {
+		phids = append(phids, transaction.PHID)
+	}
+	return phids
+}
+
+// Event is a webhook request, with the object and transactions it refers to
+// when the Handler was asked to fetch them.
+type Event struct {
+	Payload *Payload
+
+	// Only one of these is set, for the type of the object, and only when
+	// the handler fetches objects.
+	Task     *golph.Task
+	Revision *golph.Revision
+	Commit   *golph.Commit
+
+	// Only set when the handler fetches transactions.
+	Transactions []golph.AppliedTransaction
+}
+
+// EventHandler handles a webhook event. Returning an error answers the request
+// with a 500, so that Phabricator tries again later.
+type EventHandler func(ctx context.Context, event *Event) error
+
+// Handler is an http.Handler for Phabricator webhooks. It checks each request
+// is signed with the webhook's HMAC key and passes it to the EventHandler
+// registered for the type of object that changed.
+type Handler struct {
+	// Key is the HMAC key shown on the webhook's page in Phabricator.
+	Key string
+
+	// Client fetches objects and transactions when asked to.
+	Client *golph.Client
+
+	// FetchObjects fetches the task, revision or commit that changed before
+	// the event is handled.
+	FetchObjects bool
+
+	// FetchTransactions fetches the transactions in the payload before the
+	// event is handled.
+	FetchTransactions bool
+
+	handlers map[string]EventHandler
+	fallback EventHandler
+}
+
+// NewHandler returns a handler for webhooks signed with key, which fetches
+// objects and transactions through client when asked to.
+func NewHandler(key string, client *golph.Client) *Handler {
+	return &Handler{Key: key, Client: client, handlers: map[string]EventHandler{}}
+}
+
+// Handle registers fn for changes to objects of the given type, like
+// golph.PHIDTypeProject.
+func (h *Handler) Handle(objectType string, fn EventHandler) {
+	if h.handlers == nil {
+		h.handlers = map[string]EventHandler{}
+	}
+	h.handlers[objectType] = fn
+}
+
+// HandleTask registers fn for changes to tasks.
+func (h *Handler) HandleTask(fn EventHandler) {
+	h.Handle(golph.PHIDTypeTask, fn)
+}
+
+// HandleRevision registers fn for changes to revisions.
+func (h *Handler) HandleRevision(fn EventHandler) {
+	h.Handle(golph.PHIDTypeRevision, fn)
+}
+
+// HandleCommit registers fn for changes to commits.
+func (h *Handler) HandleCommit(fn EventHandler) {
+	h.Handle(golph.PHIDTypeCommit, fn)
+}
+
+// HandleOther registers fn for changes to objects no other handler is
+// registered for. Without one, those requests are acknowledged and dropped.
+func (h *Handler) HandleOther(fn EventHandler) {
+	h.fallback = fn
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !Verify(body, r.Header.Get(SignatureHeader), h.Key) {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	payload := new(Payload)
+	if err := json.Unmarshal(body, payload); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	fn := h.handlers[payload.Object.Type]
+	if fn == nil {
+		fn = h.fallback
+	}
+	if fn == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := h.event(r.Context(), payload)
+	if err == nil {
+		err = fn(r.Context(), event)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify reports whether signature is the hex encoded HMAC-SHA256 of body
+// under key.
+func Verify(body []byte, signature string, key string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(expected) != sha256.Size {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// Sign returns the signature Phabricator sends for body under key.
+func Sign(body []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// event builds the event for payload, fetching what the handler asks for.
+func (h *Handler) event(ctx context.Context, payload *Payload) (*Event, error) {
+	event := &Event{Payload: payload}
+
+	if h.FetchObjects {
+		if err := h.fetchObject(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.FetchTransactions && len(payload.Transactions) > 0 {
+		transactions, err := h.fetchTransactions(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		event.Transactions = transactions
+	}
+
+	return event, nil
+}
+
+func (h *Handler) fetchObject(ctx context.Context, event *Event) error {
+	phids := []string{event.Payload.Object.PHID}
+
+	switch event.Payload.Object.Type {
+	case golph.PHIDTypeTask:
+		tasks, _, err := h.Client.Tasks.Find(ctx, &golph.TaskFindRequest{
+			Constraints: &golph.TaskFindConstraints{PHIDs: phids},
+			Attachments: &golph.TaskFindAttachments{Columns: true, Projects: true, Subscribers: true},
+		})
+		if err != nil {
+			return err
+		}
+		if len(tasks) > 0 {
+			event.Task = &tasks[0]
+		}
+
+	case golph.PHIDTypeRevision:
+		revisions, _, err := h.Client.Differential.SearchRevisions(ctx, &golph.RevisionSearchRequest{
+			Constraints: &golph.RevisionSearchConstraints{PHIDs: phids},
+		})
+		if err != nil {
+			return err
+		}
+		if len(revisions) > 0 {
+			event.Revision = &revisions[0]
+		}
+
+	case golph.PHIDTypeCommit:
+		commits, _, err := h.Client.Repositories.SearchCommits(ctx, &golph.CommitSearchRequest{
+			Constraints: &golph.CommitSearchConstraints{PHIDs: phids},
+		})
+		if err != nil {
+			return err
+		}
+		if len(commits) > 0 {
+			event.Commit = &commits[0]
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) fetchTransactions(ctx context.Context, payload *Payload) ([]golph.AppliedTransaction, error) {
+	transactions, _, err := h.Client.Transactions.Search(ctx, &golph.TransactionSearchRequest{
+		ObjectIdentifier: payload.Object.PHID,
+		Constraints:      &golph.TransactionSearchConstraints{PHIDs: payload.TransactionPHIDs()},
+	})
+	return transactions, err
+}
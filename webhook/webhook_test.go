@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jshirley/golph"
+)
+
+const (
+	testKey         = "webhook key"
+	taskPayloadJSON = `{"object":{"type":"TASK","phid":"PHID-TASK-1"},"triggers":[{"phid":"PHID-HRUL-1"}],"action":{"test":false,"silent":false,"secure":false,"epoch":1536000000},"transactions":[{"phid":"PHID-XACT-TASK-1"},{"phid":"PHID-XACT-TASK-2"}]}`
+)
+
+var (
+	// mux is the HTTP request multiplexer used with the test server.
+	mux *http.ServeMux
+
+	// client is the Phabricator client being tested.
+	client *golph.Client
+
+	// server is a test HTTP server used to provide mock API responses.
+	server *httptest.Server
+)
+
+func setup() {
+	mux = http.NewServeMux()
+	server = httptest.NewServer(mux)
+	client = golph.NewClient("api token goes here", server.URL, nil)
+}
+
+func teardown() {
+	server.Close()
+}
+
+// post sends body to handler signed with key, returning the status code.
+func post(handler http.Handler, body string, key string) int {
+	r := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	r.Header.Set(SignatureHeader, Sign([]byte(body), key))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(taskPayloadJSON)
+	signature := Sign(body, testKey)
+
+	if !Verify(body, signature, testKey) {
+		t.Errorf("Verify rejected a valid signature")
+	}
+
+	if Verify(body, signature, "other key") || Verify([]byte("{}"), signature, testKey) || Verify(body, "not hex", testKey) || Verify(body, "", testKey) {
+		t.Errorf("Verify accepted an invalid signature")
+	}
+}
+
+func TestHandler_Dispatch(t *testing.T) {
+	handler := NewHandler(testKey, nil)
+
+	var tasks, others []string
+	handler.HandleTask(func(ctx context.Context, event *Event) error {
+		tasks = append(tasks, event.Payload.Object.PHID)
+		return nil
+	})
+	handler.HandleRevision(func(ctx context.Context, event *Event) error {
+		return errors.New("revisions are broken")
+	})
+
+	if code := post(handler, taskPayloadJSON, testKey); code != http.StatusNoContent {
+		t.Errorf("Handler answered a task event with %d, expected %d", code, http.StatusNoContent)
+	}
+
+	if code := post(handler, `{"object":{"type":"DREV","phid":"PHID-DREV-1"}}`, testKey); code != http.StatusInternalServerError {
+		t.Errorf("Handler answered a failed revision event with %d, expected %d", code, http.StatusInternalServerError)
+	}
+
+	if code := post(handler, `{"object":{"type":"PSTE","phid":"PHID-PSTE-1"}}`, testKey); code != http.StatusNoContent {
+		t.Errorf("Handler answered an unhandled event with %d, expected %d", code, http.StatusNoContent)
+	}
+
+	handler.HandleOther(func(ctx context.Context, event *Event) error {
+		others = append(others, event.Payload.Object.Type)
+		return nil
+	})
+	post(handler, `{"object":{"type":"PSTE","phid":"PHID-PSTE-1"}}`, testKey)
+
+	if fmt.Sprint(tasks) != "[PHID-TASK-1]" || fmt.Sprint(others) != "[PSTE]" {
+		t.Errorf("Handler dispatched tasks %v and others %v", tasks, others)
+	}
+}
+
+func TestHandler_Rejects(t *testing.T) {
+	handler := NewHandler(testKey, nil)
+	handler.HandleTask(func(ctx context.Context, event *Event) error {
+		t.Errorf("Handler dispatched %+v, expected it rejected", event.Payload)
+		return nil
+	})
+
+	if code := post(handler, taskPayloadJSON, "wrong key"); code != http.StatusUnauthorized {
+		t.Errorf("Handler answered a badly signed request with %d, expected %d", code, http.StatusUnauthorized)
+	}
+
+	if code := post(handler, `{"object":`, testKey); code != http.StatusBadRequest {
+		t.Errorf("Handler answered a bad payload with %d, expected %d", code, http.StatusBadRequest)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/webhook", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Handler answered a GET with %d, expected %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandler_Fetch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/maniphest.search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"data":[{"id":1,"phid":"PHID-TASK-1","fields":{"name":"Hooked"}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	mux.HandleFunc("/api/transaction.search", func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]interface{}{}
+		json.Unmarshal([]byte(r.PostFormValue("params")), &params)
+		if params["objectIdentifier"] != "PHID-TASK-1" || fmt.Sprint(params["constraints"]) != "map[phids:[PHID-XACT-TASK-1 PHID-XACT-TASK-2]]" {
+			t.Errorf("transaction.search was sent %v", params)
+		}
+		fmt.Fprint(w, `{"result":{"data":[{"id":5,"phid":"PHID-XACT-TASK-1","type":"status","authorPHID":"PHID-USER-1","objectPHID":"PHID-TASK-1","dateCreated":1536000000,"fields":{"old":"open","new":"resolved"}}],"cursor":{"after":null}},"error_code":null,"error_info":null}`)
+	})
+
+	handler := NewHandler(testKey, client)
+	handler.FetchObjects = true
+	handler.FetchTransactions = true
+
+	var event *Event
+	handler.HandleTask(func(ctx context.Context, e *Event) error {
+		event = e
+		return nil
+	})
+
+	if code := post(handler, taskPayloadJSON, testKey); code != http.StatusNoContent {
+		t.Fatalf("Handler answered %d, expected %d", code, http.StatusNoContent)
+	}
+
+	if event.Task == nil || event.Task.Title != "Hooked" {
+		t.Errorf("Handler fetched task %+v, expected Hooked", event.Task)
+	}
+
+	if len(event.Transactions) != 1 || event.Transactions[0].Type != "status" || event.Transactions[0].New != "resolved" {
+		t.Errorf("Handler fetched transactions %+v, expected the status change", event.Transactions)
+	}
+}